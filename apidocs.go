@@ -0,0 +1,48 @@
+package main
+
+import (
+	"net/http"
+	"regexp"
+)
+
+// apiDocsFormats recognise API descriptions by their version or schema
+// markers, in JSON or YAML form.
+var apiDocsFormats = []struct {
+	format string
+	re     *regexp.Regexp
+}{
+	{"swagger2", regexp.MustCompile(`(?m)"swagger"\s*:\s*"2\.|^swagger:\s*["']?2\.`)},
+	{"openapi3", regexp.MustCompile(`(?m)"openapi"\s*:\s*"3\.|^openapi:\s*["']?3\.`)},
+	{"postman", regexp.MustCompile(`"_postman_id"\s*:|schema\.getpostman\.com/json/collection`)},
+	{"wadl", regexp.MustCompile(`<application[^>]+xmlns="http://wadl\.dev\.java\.net/`)},
+}
+
+// apiDocsFormat returns the format of the API description in body, or ""
+// if body isn't one.
+func apiDocsFormat(body []byte) string {
+	for _, f := range apiDocsFormats {
+		if f.re.Match(body) {
+			return f.format
+		}
+	}
+	return ""
+}
+
+var apiDocsProbe = &hostProbe{
+	label: "API-DOCS-EXPOSED",
+	paths: []string{
+		"/swagger.json",
+		"/swagger.yaml",
+		"/api-docs",
+		"/openapi.json",
+		"/v2/api-docs",
+		"/collection.json",
+		"/application.wadl",
+	},
+	match: func(resp *http.Response, body []byte) bool {
+		return resp.StatusCode == http.StatusOK && apiDocsFormat(body) != ""
+	},
+	detail: func(_ *http.Response, body []byte) string {
+		return apiDocsFormat(body)
+	},
+}
@@ -0,0 +1,37 @@
+package main
+
+import (
+	"encoding/base64"
+	"fmt"
+	"strings"
+)
+
+// authHeader returns the Authorization header to send with every request
+// for --auth and --auth-type, or "" for schemes that are negotiated per
+// request instead.
+func authHeader(authType, value string) (string, error) {
+	switch authType {
+	case "basic":
+		return basicAuthHeader(value)
+	case "bearer":
+		return bearerAuthHeader(value), nil
+	case "digest":
+		if !strings.Contains(value, ":") {
+			return "", fmt.Errorf("digest credentials must be username:password")
+		}
+		return "", nil
+	default:
+		return "", fmt.Errorf("unknown auth type: %s", authType)
+	}
+}
+
+func basicAuthHeader(value string) (string, error) {
+	if !strings.Contains(value, ":") {
+		return "", fmt.Errorf("basic credentials must be username:password")
+	}
+	return "Authorization: Basic " + base64.StdEncoding.EncodeToString([]byte(value)), nil
+}
+
+func bearerAuthHeader(token string) string {
+	return "Authorization: Bearer " + token
+}
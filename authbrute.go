@@ -0,0 +1,122 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ahmetburakakay/urlfetcher/fetcher"
+	"golang.org/x/time/rate"
+)
+
+type credential struct {
+	username string
+	password string
+}
+
+// loadCredentials reads "username:password" pairs from a file, one per line.
+// Blank lines and lines without a colon are skipped.
+func loadCredentials(filename string) ([]credential, error) {
+	f, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var creds []credential
+	sc := bufio.NewScanner(f)
+	for sc.Scan() {
+		parts := strings.SplitN(strings.TrimSpace(sc.Text()), ":", 2)
+		if len(parts) != 2 {
+			continue
+		}
+		creds = append(creds, credential{username: parts[0], password: parts[1]})
+	}
+
+	return creds, sc.Err()
+}
+
+// hostLimiters hands out one rate.Limiter per hostname so that repeated
+// attempts against a single host can be throttled independently of the
+// global request delay.
+type hostLimiters struct {
+	sync.Mutex
+	every    time.Duration
+	limiters map[string]*rate.Limiter
+}
+
+func newHostLimiters(every time.Duration) *hostLimiters {
+	return &hostLimiters{
+		every:    every,
+		limiters: make(map[string]*rate.Limiter),
+	}
+}
+
+func (h *hostLimiters) Wait(host string) error {
+	h.Lock()
+	l, ok := h.limiters[host]
+	if !ok {
+		l = rate.NewLimiter(rate.Every(h.every), 1)
+		h.limiters[host] = l
+	}
+	h.Unlock()
+
+	return l.Wait(context.Background())
+}
+
+// isBasicChallenge reports whether resp is a 401 asking for HTTP Basic auth.
+func isBasicChallenge(resp *http.Response) bool {
+	if resp.StatusCode != http.StatusUnauthorized {
+		return false
+	}
+	for _, v := range resp.Header.Values("WWW-Authenticate") {
+		if strings.HasPrefix(strings.ToLower(strings.TrimSpace(v)), "basic") {
+			return true
+		}
+	}
+	return false
+}
+
+// bruteBasicAuth replays the request with each credential pair until the
+// server stops answering 401.
+func bruteBasicAuth(client *http.Client, limiters *hostLimiters, method, rawURL, body string, headers headerArgs, creds []credential) {
+	for _, c := range creds {
+		var b io.Reader
+		if body != "" {
+			b = strings.NewReader(body)
+		}
+
+		req, err := fetcher.NewRequest(method, rawURL, b, headers)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "failed to create request: %s\n", err)
+			return
+		}
+		req.SetBasicAuth(c.username, c.password)
+
+		err = limiters.Wait(req.URL.Hostname())
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "rate limiter error: %s\n", err)
+			return
+		}
+
+		resp, err := client.Do(req)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "request failed: %s\n", err)
+			return
+		}
+		io.Copy(ioutil.Discard, resp.Body)
+		resp.Body.Close()
+
+		if resp.StatusCode != http.StatusUnauthorized {
+			fmt.Printf("CREDS-FOUND:%s:%s %s %d\n", c.username, c.password, rawURL, resp.StatusCode)
+			return
+		}
+	}
+}
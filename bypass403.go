@@ -0,0 +1,118 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+)
+
+// bypassVariant is one way of re-requesting a forbidden URL.
+type bypassVariant struct {
+	technique string
+	url       string
+	headers   headerArgs
+	// viaRoot variants request / and rely on a header to route to the
+	// original path, so they must also differ from the plain root page.
+	viaRoot bool
+}
+
+// bypassVariants returns the 403 bypass variants of u.
+func bypassVariants(u *url.URL, headers headerArgs) []bypassVariant {
+	p := u.EscapedPath()
+	if p == "" {
+		p = "/"
+	}
+	query := ""
+	if u.RawQuery != "" {
+		query = "?" + u.RawQuery
+	}
+	origin := u.Scheme + "://" + u.Host
+	trimmed := strings.TrimPrefix(p, "/")
+
+	return []bypassVariant{
+		{technique: "X-Original-URL", url: origin + "/", headers: withHeader(headers, "X-Original-URL", p+query), viaRoot: true},
+		{technique: "X-Rewrite-URL", url: origin + "/", headers: withHeader(headers, "X-Rewrite-URL", p+query), viaRoot: true},
+		{technique: "double-slash", url: origin + "//" + trimmed + query, headers: headers},
+		{technique: "dot-segment", url: origin + "/./" + trimmed + query, headers: headers},
+		{technique: "encoded-slash", url: origin + "/" + strings.ReplaceAll(trimmed, "/", "%2F") + query, headers: headers},
+		{technique: "X-Forwarded-For", url: origin + p + query, headers: withHeader(headers, "X-Forwarded-For", "127.0.0.1")},
+	}
+}
+
+// bypassResult is a bypass variant that got through, with its status.
+type bypassResult struct {
+	technique string
+	status    int
+}
+
+// tryBypassVariants requests each of variants of the forbidden URL u and
+// returns those that get a non-error response. viaRoot variants must also
+// differ from the root page they were sent to.
+func tryBypassVariants(client *http.Client, r request, u *url.URL, headers headerArgs, variants []bypassVariant) []bypassResult {
+	var root *http.Response
+	var rootBody []byte
+
+	var results []bypassResult
+	for _, v := range variants {
+		resp, body, err := probe(client, r.method, v.url, r.body, v.headers)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "request failed: %s\n", err)
+			continue
+		}
+		if resp.StatusCode >= 400 {
+			continue
+		}
+
+		if v.viaRoot {
+			if root == nil {
+				root, rootBody, err = probe(client, r.method, u.Scheme+"://"+u.Host+"/", r.body, headers)
+				if err != nil {
+					fmt.Fprintf(os.Stderr, "request failed: %s\n", err)
+					continue
+				}
+			}
+			if !responsesDiffer(root.StatusCode, rootBody, resp.StatusCode, body) {
+				continue
+			}
+		}
+
+		results = append(results, bypassResult{v.technique, resp.StatusCode})
+	}
+	return results
+}
+
+// detectForbiddenBypass retries a URL that returned 403 with each bypass
+// variant and reports variants that get a non-error response instead.
+func detectForbiddenBypass(client *http.Client, r request, headers headerArgs) {
+	u, err := url.Parse(r.url)
+	if err != nil {
+		return
+	}
+
+	for _, b := range tryBypassVariants(client, r, u, headers, bypassVariants(u, headers)) {
+		fmt.Printf("BYPASS-POSSIBLE:%s %s (403 -> %d)\n", b.technique, r.url, b.status)
+	}
+}
+
+// detectPathOverride retries a URL that returned 403 with only the
+// X-Original-URL and X-Rewrite-URL variants, which request / and ask a
+// reverse proxy to route to the original path instead.
+func detectPathOverride(client *http.Client, r request, headers headerArgs) {
+	u, err := url.Parse(r.url)
+	if err != nil {
+		return
+	}
+
+	var variants []bypassVariant
+	for _, v := range bypassVariants(u, headers) {
+		if v.viaRoot {
+			variants = append(variants, v)
+		}
+	}
+
+	for _, b := range tryBypassVariants(client, r, u, headers, variants) {
+		fmt.Printf("PATH-OVERRIDE-BYPASS:%s %s (403 -> %d)\n", b.technique, r.url, b.status)
+	}
+}
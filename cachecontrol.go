@@ -0,0 +1,46 @@
+package main
+
+import (
+	"net/http"
+	"strings"
+)
+
+// sendsCredentials reports whether headers carry an Authorization or
+// X-Auth-Token header, marking the response as user specific.
+func sendsCredentials(headers headerArgs) bool {
+	for _, h := range headers {
+		name, _, _ := strings.Cut(h, ":")
+		name = strings.TrimSpace(name)
+		if strings.EqualFold(name, "Authorization") || strings.EqualFold(name, "X-Auth-Token") {
+			return true
+		}
+	}
+	return false
+}
+
+// cacheControlIssue classifies the caching policy of a sensitive response
+// as CACHE-CONTROL-MISSING when there is no Cache-Control header (or legacy
+// Pragma: no-cache), CACHE-CONTROL-PERMISSIVE when it allows shared caches
+// to store the response, and "" when it is adequate.
+func cacheControlIssue(resp *http.Response) string {
+	values := resp.Header.Values("Cache-Control")
+	if len(values) == 0 {
+		if strings.EqualFold(strings.TrimSpace(resp.Header.Get("Pragma")), "no-cache") {
+			return ""
+		}
+		return "CACHE-CONTROL-MISSING"
+	}
+
+	directives := make(map[string]bool)
+	for _, v := range values {
+		for _, d := range strings.Split(v, ",") {
+			name, _, _ := strings.Cut(strings.TrimSpace(d), "=")
+			directives[strings.ToLower(name)] = true
+		}
+	}
+
+	if directives["public"] || !(directives["no-store"] || directives["no-cache"] || directives["private"]) {
+		return "CACHE-CONTROL-PERMISSIVE"
+	}
+	return ""
+}
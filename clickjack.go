@@ -0,0 +1,32 @@
+package main
+
+import (
+	"net/http"
+	"strings"
+)
+
+// clickjackingProtection classifies the framing protections on resp as
+// CLICKJACK-PROTECTED when both X-Frame-Options and a CSP frame-ancestors
+// directive are present, CLICKJACK-PARTIAL when only one of them is, and
+// CLICKJACK-UNPROTECTED otherwise.
+func clickjackingProtection(resp *http.Response) string {
+	xfo := strings.TrimSpace(resp.Header.Get("X-Frame-Options")) != ""
+
+	frameAncestors := false
+	for _, csp := range resp.Header.Values("Content-Security-Policy") {
+		for _, directive := range strings.Split(csp, ";") {
+			if strings.HasPrefix(strings.ToLower(strings.TrimSpace(directive)), "frame-ancestors") {
+				frameAncestors = true
+			}
+		}
+	}
+
+	switch {
+	case xfo && frameAncestors:
+		return "CLICKJACK-PROTECTED"
+	case xfo || frameAncestors:
+		return "CLICKJACK-PARTIAL"
+	default:
+		return "CLICKJACK-UNPROTECTED"
+	}
+}
@@ -0,0 +1,106 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/ahmetburakakay/urlfetcher/fetcher"
+)
+
+// clientPool hands out the client to use for each host. Hosts covered by
+// a --proxy-map entry get a client that goes through that entry's proxy;
+// every other host shares the default client, which uses --proxy if set.
+type clientPool struct {
+	def     *http.Client
+	clients map[string]*http.Client
+	proxies map[string]string
+	proxy   string
+}
+
+// newClientPool creates the default client from opts and one client per
+// domain in proxyMap, each identical to the default but for its proxy.
+func newClientPool(opts fetcher.ClientOptions, proxyMap map[string]string) *clientPool {
+	p := &clientPool{
+		def:     fetcher.NewClient(opts),
+		clients: make(map[string]*http.Client, len(proxyMap)),
+		proxies: proxyMap,
+		proxy:   opts.Proxy,
+	}
+	for domain, proxy := range proxyMap {
+		o := opts
+		o.Proxy = proxy
+		p.clients[domain] = fetcher.NewClient(o)
+	}
+	return p
+}
+
+// domain returns the most specific --proxy-map domain that hostname is or
+// is a subdomain of, or "" if there is none.
+func (p *clientPool) domain(hostname string) string {
+	hostname = strings.ToLower(strings.TrimSuffix(hostname, "."))
+	for h := hostname; h != ""; {
+		if _, ok := p.clients[h]; ok {
+			return h
+		}
+		_, h, _ = strings.Cut(h, ".")
+	}
+	return ""
+}
+
+// Get returns the client for requests to hostname.
+func (p *clientPool) Get(hostname string) *http.Client {
+	if d := p.domain(hostname); d != "" {
+		return p.clients[d]
+	}
+	return p.def
+}
+
+// Proxy returns the proxy URL used for requests to hostname, or "".
+func (p *clientPool) Proxy(hostname string) string {
+	if d := p.domain(hostname); d != "" {
+		return p.proxies[d]
+	}
+	return p.proxy
+}
+
+// SetJar makes every client in the pool share jar.
+func (p *clientPool) SetJar(jar http.CookieJar) {
+	p.def.Jar = jar
+	for _, c := range p.clients {
+		c.Jar = jar
+	}
+}
+
+// parseProxyMap parses a --proxy-map value: comma-separated domain=proxy
+// pairs, such as "example.com=socks5://127.0.0.1:1080".
+func parseProxyMap(s string) (map[string]string, error) {
+	m := make(map[string]string)
+	for _, entry := range strings.Split(s, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		domain, proxy, ok := strings.Cut(entry, "=")
+		domain = strings.ToLower(strings.TrimSpace(domain))
+		proxy = strings.TrimSpace(proxy)
+		if !ok || domain == "" || proxy == "" {
+			return nil, fmt.Errorf("expected domain=proxy, got %q", entry)
+		}
+
+		u, err := url.Parse(proxy)
+		if err != nil || u.Host == "" {
+			return nil, fmt.Errorf("invalid proxy for %s: %q", domain, proxy)
+		}
+		switch u.Scheme {
+		case "http", "https", "socks5", "socks5h":
+		default:
+			return nil, fmt.Errorf("unsupported proxy scheme for %s: %q", domain, u.Scheme)
+		}
+
+		m[domain] = proxy
+	}
+	return m, nil
+}
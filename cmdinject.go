@@ -0,0 +1,68 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"time"
+)
+
+// commandInjectionPayloads are appended to parameter values as-is. Each one
+// tries to run "sleep 10" through a different shell separator.
+var commandInjectionPayloads = []string{
+	"%3Bsleep+10",
+	"%26%26sleep+10",
+	"%60sleep+10%60",
+}
+
+// detectCommandInjection appends each sleep payload to every query
+// parameter of r and reports parameters whose response takes at least
+// threshold. Timeouts count as slow responses; URLs whose baseline was
+// already slower than threshold are skipped.
+func detectCommandInjection(client *http.Client, r request, headers headerArgs, baseline, threshold time.Duration) {
+	if baseline >= threshold {
+		return
+	}
+
+	u, err := url.Parse(r.url)
+	if err != nil {
+		return
+	}
+
+	found := make(map[string]bool)
+	for _, payload := range commandInjectionPayloads {
+		variants := rewriteParams(u, func(_, v string) string {
+			return v + payload
+		})
+
+		for _, v := range variants {
+			if found[v.param] {
+				continue
+			}
+
+			// Time spent waiting for --delay or --rate-limit isn't the
+			// server's, so it's kept out of the measurement.
+			err := probeLimiter.Wait(context.Background())
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "rate limiter error: %s\n", err)
+				return
+			}
+
+			start := time.Now()
+			_, _, err = sendProbe(client, r.method, v.url, r.body, headers)
+			elapsed := time.Since(start)
+
+			if elapsed >= threshold {
+				fmt.Printf("COMMAND-INJECTION-POSSIBLE:%s %s (%s)\n", v.param, v.url, elapsed.Round(time.Millisecond))
+				found[v.param] = true
+				continue
+			}
+
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "request failed: %s\n", err)
+			}
+		}
+	}
+}
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+const defaultConfigPath = "~/.urlFetcher.yaml"
+
+// loadConfig sets flags from the YAML file at path, which maps flag names
+// to values. Lists set repeatable flags such as header once per element.
+// It must run after flag.Parse: flags given on the command line, under any
+// of their names, are left alone so that the command line replaces rather
+// than adds to the config. A missing file is not an error.
+func loadConfig(path string) error {
+	if rest, ok := strings.CutPrefix(path, "~/"); ok {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return nil
+		}
+		path = filepath.Join(home, rest)
+	}
+
+	data, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	var values map[string]interface{}
+	if err := yaml.Unmarshal(data, &values); err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+
+	// Aliases such as -H and --header share a Value.
+	given := make(map[flag.Value]bool)
+	flag.Visit(func(f *flag.Flag) { given[f.Value] = true })
+
+	for name, value := range values {
+		if name == "config" {
+			continue
+		}
+		if f := flag.Lookup(name); f != nil && given[f.Value] {
+			continue
+		}
+
+		list, ok := value.([]interface{})
+		if !ok {
+			list = []interface{}{value}
+		}
+
+		for _, v := range list {
+			if err := flag.Set(name, fmt.Sprint(v)); err != nil {
+				return fmt.Errorf("%s: %s: %w", path, name, err)
+			}
+		}
+	}
+
+	return nil
+}
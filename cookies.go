@@ -0,0 +1,128 @@
+package main
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/cookiejar"
+	"net/url"
+	"sync"
+	"time"
+)
+
+// persistentJar is a cookiejar.Jar that also remembers every cookie it has
+// been given so the jar can be written to and restored from disk. Like the
+// jar it wraps, it is safe for concurrent use.
+type persistentJar struct {
+	*cookiejar.Jar
+
+	mu      sync.Mutex
+	cookies map[string]savedCookie
+}
+
+type savedCookie struct {
+	URL      string    `json:"url"`
+	Name     string    `json:"name"`
+	Value    string    `json:"value"`
+	Path     string    `json:"path,omitempty"`
+	Domain   string    `json:"domain,omitempty"`
+	Expires  time.Time `json:"expires"`
+	Secure   bool      `json:"secure,omitempty"`
+	HttpOnly bool      `json:"http_only,omitempty"`
+}
+
+func newPersistentJar() (*persistentJar, error) {
+	jar, err := cookiejar.New(nil)
+	if err != nil {
+		return nil, err
+	}
+	return &persistentJar{Jar: jar, cookies: make(map[string]savedCookie)}, nil
+}
+
+func (j *persistentJar) SetCookies(u *url.URL, cookies []*http.Cookie) {
+	j.Jar.SetCookies(u, cookies)
+
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	for _, c := range cookies {
+		domain := c.Domain
+		if domain == "" {
+			domain = u.Hostname()
+		}
+		key := domain + ";" + c.Path + ";" + c.Name
+
+		if c.MaxAge < 0 || (!c.Expires.IsZero() && c.Expires.Before(time.Now())) {
+			delete(j.cookies, key)
+			continue
+		}
+
+		expires := c.Expires
+		if c.MaxAge > 0 {
+			expires = time.Now().Add(time.Duration(c.MaxAge) * time.Second)
+		}
+
+		j.cookies[key] = savedCookie{
+			URL:      u.String(),
+			Name:     c.Name,
+			Value:    c.Value,
+			Path:     c.Path,
+			Domain:   c.Domain,
+			Expires:  expires,
+			Secure:   c.Secure,
+			HttpOnly: c.HttpOnly,
+		}
+	}
+}
+
+// Load adds the cookies previously written by Save to the jar.
+func (j *persistentJar) Load(filename string) error {
+	data, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return err
+	}
+
+	var saved []savedCookie
+	err = json.Unmarshal(data, &saved)
+	if err != nil {
+		return err
+	}
+
+	for _, s := range saved {
+		u, err := url.Parse(s.URL)
+		if err != nil {
+			continue
+		}
+		j.SetCookies(u, []*http.Cookie{{
+			Name:     s.Name,
+			Value:    s.Value,
+			Path:     s.Path,
+			Domain:   s.Domain,
+			Expires:  s.Expires,
+			Secure:   s.Secure,
+			HttpOnly: s.HttpOnly,
+		}})
+	}
+
+	return nil
+}
+
+// Save writes every unexpired cookie in the jar to filename as JSON.
+func (j *persistentJar) Save(filename string) error {
+	j.mu.Lock()
+	saved := make([]savedCookie, 0, len(j.cookies))
+	for _, c := range j.cookies {
+		if !c.Expires.IsZero() && c.Expires.Before(time.Now()) {
+			continue
+		}
+		saved = append(saved, c)
+	}
+	j.mu.Unlock()
+
+	data, err := json.MarshalIndent(saved, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	return ioutil.WriteFile(filename, data, 0600)
+}
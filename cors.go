@@ -0,0 +1,186 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+)
+
+// corsAllowsOrigin reports whether resp grants cross-origin access to origin
+// through its Access-Control-Allow-Origin header.
+func corsAllowsOrigin(resp *http.Response, origin string) bool {
+	return strings.TrimSpace(resp.Header.Get("Access-Control-Allow-Origin")) == origin
+}
+
+// corsAllowsCredentials reports whether resp allows credentialed
+// cross-origin requests.
+func corsAllowsCredentials(resp *http.Response) bool {
+	return strings.EqualFold(strings.TrimSpace(resp.Header.Get("Access-Control-Allow-Credentials")), "true")
+}
+
+// corsProbeOrigin is sent as the Origin of CORS probes. It is on a reserved
+// domain so that it can never legitimately be trusted.
+const corsProbeOrigin = "https://urlfetcher-cors-probe.example"
+
+// corsPreflight sends an OPTIONS preflight asking whether origin may issue a
+// cross-origin request with the given method.
+func corsPreflight(client *http.Client, rawURL, origin, method string, headers headerArgs) (*http.Response, error) {
+	h := withHeader(headers, "Origin", origin)
+	h = withHeader(h, "Access-Control-Request-Method", method)
+
+	resp, _, err := probe(client, http.MethodOptions, rawURL, "", h)
+	return resp, err
+}
+
+// corsAllowedMethods returns the upper-cased methods listed in resp's
+// Access-Control-Allow-Methods header.
+func corsAllowedMethods(resp *http.Response) []string {
+	var methods []string
+	for _, v := range resp.Header.Values("Access-Control-Allow-Methods") {
+		for _, m := range strings.Split(v, ",") {
+			if m = strings.ToUpper(strings.TrimSpace(m)); m != "" {
+				methods = append(methods, m)
+			}
+		}
+	}
+	return methods
+}
+
+// detectDangerousCORSMethods sends a DELETE preflight from an untrusted origin and
+// reports when the response allows DELETE, PUT or PATCH cross-origin.
+func detectDangerousCORSMethods(client *http.Client, rawURL string, headers headerArgs) {
+	resp, err := corsPreflight(client, rawURL, corsProbeOrigin, http.MethodDelete, headers)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "request failed: %s\n", err)
+		return
+	}
+
+	if !corsAllowsOrigin(resp, corsProbeOrigin) && !corsAllowsOrigin(resp, "*") {
+		return
+	}
+
+	var dangerous []string
+	for _, m := range corsAllowedMethods(resp) {
+		switch m {
+		case "*", http.MethodDelete, http.MethodPut, http.MethodPatch:
+			dangerous = append(dangerous, m)
+		}
+	}
+
+	if len(dangerous) > 0 {
+		fmt.Printf("CORS-DANGEROUS-METHOD %s %s\n", rawURL, strings.Join(dangerous, ","))
+	}
+}
+
+// corsUnsafeMethods are the mutation methods tried by
+// detectUnsafeCORSMethods.
+var corsUnsafeMethods = []string{http.MethodPut, http.MethodDelete, http.MethodPatch}
+
+// detectUnsafeCORSMethods sends one preflight from an untrusted origin per
+// method in corsUnsafeMethods and reports each method the response grants.
+// Unlike detectDangerousCORSMethods, this catches servers that only allow
+// the method that was asked for.
+func detectUnsafeCORSMethods(client *http.Client, rawURL string, headers headerArgs) {
+	for _, method := range corsUnsafeMethods {
+		resp, err := corsPreflight(client, rawURL, corsProbeOrigin, method, headers)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "request failed: %s\n", err)
+			return
+		}
+
+		if !corsAllowsOrigin(resp, corsProbeOrigin) && !corsAllowsOrigin(resp, "*") {
+			continue
+		}
+
+		for _, m := range corsAllowedMethods(resp) {
+			if m == method || m == "*" {
+				fmt.Printf("CORS-UNSAFE-METHOD-ALLOWED:%s %s\n", method, rawURL)
+				break
+			}
+		}
+	}
+}
+
+// detectCORSSubdomain sends rawURL an Origin on a made-up subdomain of the
+// target's registrable domain and reports when it is trusted, which means
+// a takeover or XSS on any subdomain can read the response cross-origin.
+func detectCORSSubdomain(client *http.Client, rawURL string, headers headerArgs) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return
+	}
+	origin := "https://evil." + registrableDomain(u.Hostname())
+
+	resp, _, err := probe(client, http.MethodGet, rawURL, "", withHeader(headers, "Origin", origin))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "request failed: %s\n", err)
+		return
+	}
+
+	if !corsAllowsOrigin(resp, origin) {
+		return
+	}
+
+	extra := origin
+	if corsAllowsCredentials(resp) {
+		extra += ", credentials"
+	}
+	fmt.Printf("CORS-SUBDOMAIN-BYPASS %s (%s)\n", rawURL, extra)
+}
+
+// detectCORSPrivateNetwork sends a Private Network Access preflight from
+// an untrusted origin and reports when the response allows it. Browsers
+// send these before a public site may reach a private address, so this
+// lets any site on the internet talk to the service from a victim's
+// network.
+func detectCORSPrivateNetwork(client *http.Client, rawURL string, headers headerArgs) {
+	h := withHeader(headers, "Access-Control-Request-Private-Network", "true")
+	resp, err := corsPreflight(client, rawURL, corsProbeOrigin, http.MethodGet, h)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "request failed: %s\n", err)
+		return
+	}
+
+	if !strings.EqualFold(strings.TrimSpace(resp.Header.Get("Access-Control-Allow-Private-Network")), "true") {
+		return
+	}
+
+	if corsAllowsOrigin(resp, corsProbeOrigin) || corsAllowsOrigin(resp, "*") {
+		fmt.Printf("CORS-PRIVATE-NETWORK %s (any origin)\n", rawURL)
+	} else {
+		fmt.Printf("CORS-PRIVATE-NETWORK %s\n", rawURL)
+	}
+}
+
+// detectCORSPreflightBypass sends r's JSON body once as application/json
+// and once as text/plain, and reports when the server handles both alike.
+// Browsers send a text/plain POST cross-origin without a preflight, so such
+// an endpoint can be reached by any site regardless of its CORS policy.
+func detectCORSPreflightBypass(client *http.Client, r request, headers headerArgs) {
+	body := strings.TrimSpace(r.body)
+	if r.method != http.MethodPost || !(strings.HasPrefix(body, "{") || strings.HasPrefix(body, "[")) || !json.Valid([]byte(body)) {
+		return
+	}
+
+	jsonResp, jsonBody, err := probe(client, r.method, r.url, r.body, withHeader(headers, "Content-Type", "application/json"))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "request failed: %s\n", err)
+		return
+	}
+	if jsonResp.StatusCode < 200 || jsonResp.StatusCode >= 300 {
+		return
+	}
+
+	textResp, textBody, err := probe(client, r.method, r.url, r.body, withHeader(headers, "Content-Type", "text/plain"))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "request failed: %s\n", err)
+		return
+	}
+
+	if !responsesDiffer(jsonResp.StatusCode, jsonBody, textResp.StatusCode, textBody) {
+		fmt.Printf("CORS-PREFLIGHT-BYPASS %s (text/plain accepted as JSON)\n", r.url)
+	}
+}
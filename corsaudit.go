@@ -0,0 +1,155 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"sort"
+	"sync"
+
+	"golang.org/x/net/publicsuffix"
+)
+
+// corsSeverities orders finding severities from most to least severe.
+var corsSeverities = map[string]int{"high": 0, "medium": 1, "low": 2}
+
+type corsFinding struct {
+	Severity    string `json:"severity"`
+	Test        string `json:"test"`
+	Origin      string `json:"origin"`
+	Credentials bool   `json:"credentials"`
+}
+
+// corsReport collects the CORS findings for each URL.
+type corsReport struct {
+	sync.Mutex
+	urls map[string][]corsFinding
+}
+
+func newCORSReport() *corsReport {
+	return &corsReport{urls: make(map[string][]corsFinding)}
+}
+
+// corsTest is one origin tried by Audit. Severity is the severity of an
+// allowed origin without and with credentials.
+type corsTest struct {
+	name     string
+	origin   string
+	severity [2]string
+}
+
+// corsTests returns the origins to try against u. Origins derived from the
+// target's registrable domain catch allow-lists that trust every subdomain,
+// or that match the domain with an unanchored prefix or suffix check.
+func corsTests(u *url.URL) []corsTest {
+	host := u.Hostname()
+	domain := registrableDomain(host)
+
+	return []corsTest{
+		{"reflected-origin", corsProbeOrigin, [2]string{"medium", "high"}},
+		{"null-origin", "null", [2]string{"medium", "high"}},
+		{"arbitrary-subdomain", u.Scheme + "://urlfetcher-cors-probe." + domain, [2]string{"low", "medium"}},
+		{"suffix-bypass", u.Scheme + "://" + domain + ".urlfetcher-cors-probe.example", [2]string{"medium", "high"}},
+		{"prefix-bypass", u.Scheme + "://urlfetcher" + domain, [2]string{"medium", "high"}},
+		{"http-origin", "http://" + host, [2]string{"low", "medium"}},
+	}
+}
+
+// registrableDomain returns the domain under a public suffix that host
+// belongs to, such as example.com for api.example.com. IP addresses and
+// hosts without a known public suffix are returned as they are.
+func registrableDomain(host string) string {
+	if net.ParseIP(host) != nil {
+		return host
+	}
+	if d, err := publicsuffix.EffectiveTLDPlusOne(host); err == nil {
+		return d
+	}
+	return host
+}
+
+// Audit sends rawURL each test origin in turn and reports and records the
+// ones that are allowed, along with a wildcard Access-Control-Allow-Origin.
+// Once an arbitrary origin is reflected, the narrower tests are skipped.
+func (c *corsReport) Audit(client *http.Client, rawURL string, headers headerArgs) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return
+	}
+
+	var findings []corsFinding
+	for _, t := range corsTests(u) {
+		if t.name == "http-origin" && u.Scheme != "https" {
+			continue
+		}
+
+		resp, _, err := probe(client, http.MethodGet, rawURL, "", withHeader(headers, "Origin", t.origin))
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "request failed: %s\n", err)
+			return
+		}
+
+		creds := corsAllowsCredentials(resp)
+
+		if t.name == "reflected-origin" && corsAllowsOrigin(resp, "*") {
+			findings = append(findings, corsFinding{Severity: "low", Test: "wildcard-origin", Origin: "*", Credentials: creds})
+			continue
+		}
+		if !corsAllowsOrigin(resp, t.origin) {
+			continue
+		}
+
+		severity := t.severity[0]
+		if creds {
+			severity = t.severity[1]
+		}
+		findings = append(findings, corsFinding{Severity: severity, Test: t.name, Origin: t.origin, Credentials: creds})
+
+		if t.name == "reflected-origin" {
+			// Every other origin will be reflected too.
+			break
+		}
+	}
+
+	sort.SliceStable(findings, func(i, j int) bool {
+		return corsSeverities[findings[i].Severity] < corsSeverities[findings[j].Severity]
+	})
+
+	for _, f := range findings {
+		credentials := ""
+		if f.Credentials {
+			credentials = ", credentials"
+		}
+		fmt.Printf("CORS-INSECURE:%s:%s %s (%s%s)\n", f.Severity, f.Test, rawURL, f.Origin, credentials)
+	}
+
+	if len(findings) > 0 {
+		c.Lock()
+		c.urls[rawURL] = findings
+		c.Unlock()
+	}
+}
+
+// WriteFile writes the collected findings as cors-report.json in the
+// output directory.
+func (c *corsReport) WriteFile(prefix string) error {
+	c.Lock()
+	defer c.Unlock()
+
+	out, err := json.MarshalIndent(c.urls, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	err = os.MkdirAll(prefix, 0750)
+	if err != nil {
+		return err
+	}
+
+	return ioutil.WriteFile(path.Join(prefix, "cors-report.json"), out, 0644)
+}
@@ -0,0 +1,74 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+)
+
+const crlfProbeHeader = "X-Urlfetcher-Crlf"
+
+// crlfSequences are appended to parameter and header values. A raw "\r\n"
+// can't be sent in either place with net/http, so the literal backslash form
+// is sent encoded for applications that unescape it themselves.
+var crlfSequences = []string{"%0d%0a", "%0D%0A", "%5cr%5cn"}
+
+// detectCRLF appends CRLF sequences followed by a probe header to each query
+// parameter and header value of r, and reports any variant where the probe
+// header comes back as a real response header.
+func detectCRLF(client *http.Client, r request, headers headerArgs) {
+	u, err := url.Parse(r.url)
+	if err != nil {
+		return
+	}
+
+	injection := crlfProbeHeader + ":%20injected"
+
+	for _, seq := range crlfSequences {
+		variants := rewriteParams(u, func(_, v string) string {
+			return v + seq + injection
+		})
+
+		for _, v := range variants {
+			resp, _, err := probe(client, r.method, v.url, r.body, headers)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "request failed: %s\n", err)
+				continue
+			}
+
+			if resp.Header.Get(crlfProbeHeader) != "" {
+				fmt.Printf("CRLF-INJECTION:%s %s\n", v.param, v.url)
+			}
+		}
+	}
+
+	names := []string{"Referer"}
+	for _, h := range headers {
+		if parts := strings.SplitN(h, ":", 2); len(parts) == 2 {
+			names = append(names, parts[0])
+		}
+	}
+
+	for _, seq := range crlfSequences {
+		for _, name := range names {
+			value := "https://" + u.Host + "/"
+			for _, h := range headers {
+				if parts := strings.SplitN(h, ":", 2); len(parts) == 2 && strings.EqualFold(parts[0], name) {
+					value = strings.TrimSpace(parts[1])
+				}
+			}
+
+			resp, _, err := probe(client, r.method, r.url, r.body, withHeader(headers, name, value+seq+injection))
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "request failed: %s\n", err)
+				continue
+			}
+
+			if resp.Header.Get(crlfProbeHeader) != "" {
+				fmt.Printf("CRLF-INJECTION:%s %s\n", name, r.url)
+			}
+		}
+	}
+}
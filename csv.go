@@ -0,0 +1,69 @@
+package main
+
+import (
+	"encoding/csv"
+	"io"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// csvResults writes one CSV row per fetched URL for --output-format csv.
+// It is safe for concurrent use.
+type csvResults struct {
+	mu    sync.Mutex
+	w     *csv.Writer
+	words bool
+	stats bool
+}
+
+// bodyStats are the --stats word and line counts of a response body.
+type bodyStats struct {
+	words int
+	lines int
+}
+
+// newCSVResults writes the header row to w. With words set, a fuzz_word
+// column records the --wordlist entry each request was made with, and with
+// stats set, wc_words and wc_lines columns record the body's --stats.
+func newCSVResults(w io.Writer, words, stats bool) *csvResults {
+	c := &csvResults{w: csv.NewWriter(w), words: words, stats: stats}
+
+	header := []string{"url", "status_code", "content_length", "response_time_ms", "content_type", "saved_path"}
+	if words {
+		header = append(header, "fuzz_word")
+	}
+	if stats {
+		header = append(header, "wc_words", "wc_lines")
+	}
+	c.w.Write(header)
+	c.w.Flush()
+
+	return c
+}
+
+// Write adds the row for r. savedPath is empty if the response wasn't
+// saved, and stats is only used with --stats.
+func (c *csvResults) Write(r request, resp *http.Response, length int, elapsed time.Duration, savedPath string, stats bodyStats) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	row := []string{
+		r.url,
+		strconv.Itoa(resp.StatusCode),
+		strconv.Itoa(length),
+		strconv.FormatInt(elapsed.Milliseconds(), 10),
+		resp.Header.Get("Content-Type"),
+		savedPath,
+	}
+	if c.words {
+		row = append(row, r.word)
+	}
+	if c.stats {
+		row = append(row, strconv.Itoa(stats.words), strconv.Itoa(stats.lines))
+	}
+	c.w.Write(row)
+	c.w.Flush()
+	return c.w.Error()
+}
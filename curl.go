@@ -0,0 +1,53 @@
+package main
+
+import (
+	"net/http"
+	"sort"
+	"strings"
+)
+
+// shellQuote quotes s for a POSIX shell. Single quotes are used so that
+// nothing inside is expanded; embedded single quotes are closed, escaped
+// and reopened.
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+// curlCommand returns a curl command line that repeats req with body, or
+// with the contents of bodyFile, going through proxy if it isn't empty.
+func curlCommand(req *http.Request, body, bodyFile, proxy string) string {
+	args := []string{"curl"}
+
+	if req.Method != http.MethodGet && !(req.Method == http.MethodPost && (body != "" || bodyFile != "")) {
+		args = append(args, "-X", shellQuote(req.Method))
+	}
+
+	names := make([]string, 0, len(req.Header))
+	for name := range req.Header {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		for _, v := range req.Header[name] {
+			args = append(args, "-H", shellQuote(name+": "+v))
+		}
+	}
+
+	if body != "" {
+		// -d reads a file when its value starts with @.
+		flag := "-d"
+		if strings.HasPrefix(body, "@") {
+			flag = "--data-raw"
+		}
+		args = append(args, flag, shellQuote(body))
+	} else if bodyFile != "" {
+		args = append(args, "--data-binary", shellQuote("@"+bodyFile))
+	}
+
+	if proxy != "" {
+		args = append(args, "-x", shellQuote(proxy))
+	}
+
+	args = append(args, shellQuote(req.URL.String()))
+	return strings.Join(args, " ")
+}
@@ -0,0 +1,198 @@
+package main
+
+import (
+	"database/sql"
+	"fmt"
+	"net/http"
+	"os"
+	"time"
+
+	_ "modernc.org/sqlite"
+)
+
+// dbBatchSize is the most rows the --db writer inserts per transaction.
+const dbBatchSize = 100
+
+// dbMigrations are applied in order to bring a database up to date. The
+// index of the last applied migration plus one is kept in the SQLite
+// user_version pragma, so entries must only ever be appended.
+var dbMigrations = []string{
+	`CREATE TABLE results (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		url TEXT NOT NULL,
+		method TEXT NOT NULL,
+		status INTEGER NOT NULL,
+		content_type TEXT NOT NULL,
+		body_size INTEGER NOT NULL,
+		response_time_ms INTEGER NOT NULL,
+		saved_path TEXT NOT NULL,
+		timestamp TEXT NOT NULL
+	)`,
+	`CREATE INDEX results_status ON results (status)`,
+}
+
+// dbRow is one row of the results table.
+type dbRow struct {
+	url         string
+	method      string
+	status      int
+	contentType string
+	bodySize    int64
+	elapsed     time.Duration
+	savedPath   string
+	timestamp   time.Time
+}
+
+// resultsDB records one row per fetched URL for --db. Rows are handed to
+// a single writer goroutine that inserts them in batches, so Insert is
+// safe for concurrent use and workers don't contend for the database.
+type resultsDB struct {
+	db   *sql.DB
+	rows chan dbRow
+	done chan struct{}
+}
+
+// openResultsDB opens or creates the SQLite database at path, applies any
+// pending migrations and starts the writer goroutine.
+func openResultsDB(path string) (*resultsDB, error) {
+	db, err := sql.Open("sqlite", path)
+	if err != nil {
+		return nil, err
+	}
+	// SQLite allows one writer at a time, and the writer goroutine is the
+	// only user of the connection.
+	db.SetMaxOpenConns(1)
+
+	err = migrateDB(db)
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	d := &resultsDB{
+		db:   db,
+		rows: make(chan dbRow, dbBatchSize),
+		done: make(chan struct{}),
+	}
+	go d.write()
+
+	return d, nil
+}
+
+func migrateDB(db *sql.DB) error {
+	var version int
+	err := db.QueryRow("PRAGMA user_version").Scan(&version)
+	if err != nil {
+		return err
+	}
+
+	for i := version; i < len(dbMigrations); i++ {
+		tx, err := db.Begin()
+		if err != nil {
+			return err
+		}
+		_, err = tx.Exec(dbMigrations[i])
+		if err == nil {
+			// PRAGMA doesn't take bound parameters.
+			_, err = tx.Exec(fmt.Sprintf("PRAGMA user_version = %d", i+1))
+		}
+		if err != nil {
+			tx.Rollback()
+			return fmt.Errorf("migration %d: %w", i+1, err)
+		}
+		err = tx.Commit()
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Insert queues the row for r. savedPath is empty if the response wasn't
+// saved.
+func (d *resultsDB) Insert(r request, resp *http.Response, size int64, elapsed time.Duration, savedPath string) {
+	d.rows <- dbRow{
+		url:         r.url,
+		method:      r.method,
+		status:      resp.StatusCode,
+		contentType: resp.Header.Get("Content-Type"),
+		bodySize:    size,
+		elapsed:     elapsed,
+		savedPath:   savedPath,
+		timestamp:   time.Now(),
+	}
+}
+
+// write inserts queued rows until Close is called. A batch is flushed as
+// soon as no more rows are waiting, so rows aren't held back when
+// requests are slow.
+func (d *resultsDB) write() {
+	defer close(d.done)
+
+	batch := make([]dbRow, 0, dbBatchSize)
+	for row := range d.rows {
+		batch = append(batch[:0], row)
+
+	fill:
+		for len(batch) < dbBatchSize {
+			select {
+			case row, ok := <-d.rows:
+				if !ok {
+					break fill
+				}
+				batch = append(batch, row)
+			default:
+				break fill
+			}
+		}
+
+		err := d.insert(batch)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "failed to write results to database: %s\n", err)
+		}
+	}
+}
+
+func (d *resultsDB) insert(batch []dbRow) error {
+	tx, err := d.db.Begin()
+	if err != nil {
+		return err
+	}
+
+	stmt, err := tx.Prepare(`INSERT INTO results
+		(url, method, status, content_type, body_size, response_time_ms, saved_path, timestamp)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?)`)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+	defer stmt.Close()
+
+	for _, row := range batch {
+		_, err = stmt.Exec(
+			row.url,
+			row.method,
+			row.status,
+			row.contentType,
+			row.bodySize,
+			row.elapsed.Milliseconds(),
+			row.savedPath,
+			row.timestamp.UTC().Format(time.RFC3339Nano),
+		)
+		if err != nil {
+			tx.Rollback()
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+// Close waits for queued rows to be written and closes the database. No
+// more rows may be inserted after Close.
+func (d *resultsDB) Close() error {
+	close(d.rows)
+	<-d.done
+	return d.db.Close()
+}
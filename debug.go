@@ -0,0 +1,44 @@
+package main
+
+import (
+	"net/http"
+	"regexp"
+	"strings"
+)
+
+// debugModePatterns are markers from the development error pages of common
+// web frameworks, which are only shown with debug mode enabled.
+var debugModePatterns = []struct {
+	framework string
+	re        *regexp.Regexp
+}{
+	{"flask", regexp.MustCompile(`Werkzeug Debugger|The debugger caught an exception in your WSGI application|\?__debugger__=yes`)},
+	{"django", regexp.MustCompile(`You're seeing this error because you have <code>DEBUG = True</code>|Using the URLconf defined in <code>`)},
+	{"rails", regexp.MustCompile(`Action Controller: Exception caught|<code>Rails\.root:|data-web-console`)},
+	{"express", regexp.MustCompile(`at Layer\.handle \[as handle_request\]|node_modules[/\\]express[/\\]lib[/\\]router`)},
+}
+
+// debugHeaderPrefixes are response headers added by framework debug
+// toolbars and profilers, such as Symfony's X-Debug-Token.
+var debugHeaderPrefixes = []string{"X-Debug", "X-Symfony-Debug", "X-Django-Debug"}
+
+// debugModeFramework returns the name of the framework whose debug mode
+// resp and body reveal, "header" if only a debug header does, or "" if
+// neither does.
+func debugModeFramework(resp *http.Response, body []byte) string {
+	for _, p := range debugModePatterns {
+		if p.re.Match(body) {
+			return p.framework
+		}
+	}
+
+	for name := range resp.Header {
+		for _, prefix := range debugHeaderPrefixes {
+			if strings.HasPrefix(name, prefix) {
+				return "header:" + name
+			}
+		}
+	}
+
+	return ""
+}
@@ -0,0 +1,43 @@
+package main
+
+import (
+	"crypto/sha256"
+	"sync"
+	"sync/atomic"
+)
+
+// responseDeduper remembers the SHA-256 of every response body it has been
+// asked about. Once more than limit hashes are held the cache is flushed,
+// trading perfect deduplication for bounded memory on long runs. A limit of
+// zero or less never flushes.
+type responseDeduper struct {
+	seen  sync.Map
+	count int64
+	limit int64
+}
+
+func newResponseDeduper(limit int) *responseDeduper {
+	return &responseDeduper{limit: int64(limit)}
+}
+
+// SeenSum records the SHA-256 of a body and reports whether an identical
+// body had already been recorded.
+func (d *responseDeduper) SeenSum(sum [sha256.Size]byte) bool {
+	if _, loaded := d.seen.LoadOrStore(sum, struct{}{}); loaded {
+		return true
+	}
+
+	if atomic.AddInt64(&d.count, 1) > d.limit && d.limit > 0 {
+		d.Flush()
+	}
+	return false
+}
+
+// Flush forgets every hash seen so far.
+func (d *responseDeduper) Flush() {
+	d.seen.Range(func(k, _ interface{}) bool {
+		d.seen.Delete(k)
+		return true
+	})
+	atomic.StoreInt64(&d.count, 0)
+}
@@ -0,0 +1,30 @@
+package main
+
+import (
+	"bytes"
+	"regexp"
+)
+
+// javaSerialMagic is the stream header written by ObjectOutputStream:
+// STREAM_MAGIC (0xaced) followed by STREAM_VERSION (0x0005). rO0AB is the
+// same header base64 encoded.
+var (
+	javaSerialMagic       = []byte{0xac, 0xed, 0x00, 0x05}
+	javaSerialMagicBase64 = []byte("rO0AB")
+)
+
+var javaDeserErrorRe = regexp.MustCompile(`java\.io\.(ObjectInputStream|InvalidClassException|StreamCorruptedException)`)
+
+// javaDeserializationEvidence returns a short description of any sign in body
+// that the server handles Java serialized objects, or "" if there is none.
+func javaDeserializationEvidence(body []byte) string {
+	switch {
+	case bytes.Contains(body, javaSerialMagic):
+		return "serialized object"
+	case bytes.Contains(body, javaSerialMagicBase64):
+		return "base64 serialized object"
+	case javaDeserErrorRe.Match(body):
+		return string(javaDeserErrorRe.Find(body))
+	}
+	return ""
+}
@@ -0,0 +1,184 @@
+package main
+
+import (
+	"bytes"
+	"net"
+	"net/http"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// disclosureCheck is a passive check run against every response. find
+// returns a short description of what was disclosed, or "" if nothing was.
+type disclosureCheck struct {
+	label string
+	find  func(resp *http.Response, body []byte) string
+}
+
+var versionRe = regexp.MustCompile(`\d+\.\d+`)
+
+// bannerHeaders are response headers that commonly name server software.
+var bannerHeaders = []string{"Server", "X-Powered-By", "X-AspNet-Version", "X-AspNetMvc-Version", "X-Generator"}
+
+// serverBannerCheck reports banner headers that include a version number.
+var serverBannerCheck = disclosureCheck{
+	label: "SERVER-BANNER",
+	find: func(resp *http.Response, _ []byte) string {
+		var found []string
+		for _, name := range bannerHeaders {
+			if v := resp.Header.Get(name); versionRe.MatchString(v) {
+				found = append(found, name+": "+v)
+			}
+		}
+		return strings.Join(found, ", ")
+	},
+}
+
+// verboseHeaders are response headers that leak details of the
+// infrastructure behind the server, such as backend hosts.
+var verboseHeaders = []string{
+	"X-Backend",
+	"X-Backend-Host",
+	"X-Backend-Server",
+	"X-Forwarded-Host",
+	"X-Forwarded-Server",
+	"X-Host",
+	"X-Instance-Id",
+	"X-Node",
+	"X-Pod-Name",
+	"X-Real-Server",
+	"X-Runtime",
+	"X-Served-By",
+	"X-Server",
+	"X-Server-Name",
+	"X-Upstream",
+	"X-Upstream-Addr",
+}
+
+var verboseHeadersCheck = disclosureCheck{
+	label: "VERBOSE-HEADERS",
+	find: func(resp *http.Response, _ []byte) string {
+		var found []string
+		for _, name := range verboseHeaders {
+			if v := resp.Header.Get(name); v != "" {
+				found = append(found, name+": "+v)
+			}
+		}
+		return strings.Join(found, ", ")
+	},
+}
+
+// namedPatterns is an ordered list of regular expressions labelled with
+// what they identify.
+type namedPatterns []struct {
+	name string
+	re   *regexp.Regexp
+}
+
+// first returns the name of the first pattern matching body, or "".
+func (ps namedPatterns) first(body []byte) string {
+	for _, p := range ps {
+		if p.re.Match(body) {
+			return p.name
+		}
+	}
+	return ""
+}
+
+var stackTracePatterns = namedPatterns{
+	{"java", regexp.MustCompile(`\tat [\w$.]+\([\w$]+\.java:\d+\)|Exception in thread "`)},
+	{"python", regexp.MustCompile(`Traceback \(most recent call last\):`)},
+	{"dotnet", regexp.MustCompile(`(?m)^\s+at [\w.<>]+\(.*\) in .+:line \d+|\[\w+Exception: .+\]\s+System\.`)},
+	{"php", regexp.MustCompile(`(?i)(Fatal error|Stack trace):.*(\n|<br />\s*)#0 |PHP (Fatal|Parse) error:`)},
+	{"go", regexp.MustCompile(`goroutine \d+ \[running\]:`)},
+	{"node", regexp.MustCompile(`(?m)^\s+at .+ \((/|[A-Z]:\\).+\.js:\d+:\d+\)`)},
+	{"ruby", regexp.MustCompile(`\.rb:\d+:in ` + "`")},
+}
+
+var stackTracesCheck = disclosureCheck{
+	label: "STACK-TRACE",
+	find: func(_ *http.Response, body []byte) string {
+		return stackTracePatterns.first(body)
+	},
+}
+
+var sqlErrorPatterns = namedPatterns{
+	{"mysql", regexp.MustCompile(`(?i)You have an error in your SQL syntax|mysql_fetch_|MySqlException|Warning: mysqli?_`)},
+	{"postgresql", regexp.MustCompile(`(?i)PG::SyntaxError|PSQLException|ERROR:\s+syntax error at or near|pg_query\(\)`)},
+	{"mssql", regexp.MustCompile(`(?i)Unclosed quotation mark after the character string|Microsoft OLE DB Provider for SQL Server|SqlException|\[SQL Server\]`)},
+	{"oracle", regexp.MustCompile(`\bORA-\d{5}\b|Oracle error`)},
+	{"sqlite", regexp.MustCompile(`(?i)SQLite3?::|sqlite3?\.OperationalError|SQLITE_ERROR`)},
+}
+
+var sqlErrorsCheck = disclosureCheck{
+	label: "SQL-ERROR",
+	find: func(_ *http.Response, body []byte) string {
+		return sqlErrorPatterns.first(body)
+	},
+}
+
+var phpInfoRe = regexp.MustCompile(`<title>phpinfo\(\)</title>|<h1 class="p">PHP Version [\d.]+</h1>`)
+
+var phpInfoCheck = disclosureCheck{
+	label: "PHPINFO",
+	find: func(_ *http.Response, body []byte) string {
+		if phpInfoRe.Match(body) {
+			return "phpinfo()"
+		}
+		return ""
+	},
+}
+
+// phpInfoProbe requests the file names phpinfo() pages are usually left at.
+var phpInfoProbe = &hostProbe{
+	label: "PHPINFO",
+	paths: []string{"/phpinfo.php", "/info.php", "/php_info.php", "/php.php", "/test.php", "/i.php"},
+	match: func(resp *http.Response, body []byte) bool {
+		return resp.StatusCode == http.StatusOK && phpInfoRe.Match(body)
+	},
+}
+
+var privateIPRe = regexp.MustCompile(`\b(10\.\d{1,3}\.\d{1,3}\.\d{1,3}|172\.(1[6-9]|2\d|3[01])\.\d{1,3}\.\d{1,3}|192\.168\.\d{1,3}\.\d{1,3})\b`)
+
+// internalIPsCheck reports RFC 1918 addresses in response headers or body.
+var internalIPsCheck = disclosureCheck{
+	label: "INTERNAL-IP",
+	find: func(resp *http.Response, body []byte) string {
+		sources := [][]byte{body}
+		for _, vs := range resp.Header {
+			for _, v := range vs {
+				sources = append(sources, []byte(v))
+			}
+		}
+
+		seen := make(map[string]bool)
+		for _, s := range sources {
+			for _, m := range privateIPRe.FindAll(s, -1) {
+				if ip := net.ParseIP(string(m)); ip != nil {
+					seen[ip.String()] = true
+				}
+			}
+		}
+
+		ips := make([]string, 0, len(seen))
+		for ip := range seen {
+			ips = append(ips, ip)
+		}
+		sort.Strings(ips)
+		return strings.Join(ips, ", ")
+	},
+}
+
+// gitExposureProbe requests files that are only served when a .git
+// directory is left in the web root.
+var gitExposureProbe = &hostProbe{
+	label: "GIT-EXPOSED",
+	paths: []string{"/.git/HEAD", "/.git/config", "/.git/index"},
+	match: func(resp *http.Response, body []byte) bool {
+		return resp.StatusCode == http.StatusOK &&
+			(bytes.HasPrefix(body, []byte("ref: refs/")) ||
+				bytes.Contains(body, []byte("[core]")) ||
+				bytes.HasPrefix(body, []byte("DIRC")))
+	},
+}
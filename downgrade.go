@@ -0,0 +1,30 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+)
+
+// detectHTTP2Downgrade fetches r over HTTP/1.1 and over HTTP/2 (h2c for
+// http:// URLs) and reports URLs whose status or body differs between the
+// two, which happens when a WAF or proxy only inspects one of them.
+func detectHTTP2Downgrade(h1, h2 *http.Client, r request, headers headerArgs) {
+	resp1, body1, err := probe(h1, r.method, r.url, r.body, headers)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "request failed: %s\n", err)
+		return
+	}
+
+	// Most servers don't speak h2c and some don't speak HTTP/2 at all;
+	// neither is interesting here, so failures and HTTP/1.x answers are
+	// skipped without a message.
+	resp2, body2, err := probe(h2, r.method, r.url, r.body, headers)
+	if err != nil || resp2.ProtoMajor != 2 {
+		return
+	}
+
+	if responsesDiffer(resp1.StatusCode, body1, resp2.StatusCode, body2) {
+		fmt.Printf("H2-DOWNGRADE-BYPASS %s (%s %d, %s %d)\n", r.url, resp1.Proto, resp1.StatusCode, resp2.Proto, resp2.StatusCode)
+	}
+}
@@ -0,0 +1,36 @@
+package main
+
+import (
+	"net/http"
+	"regexp"
+
+	"github.com/ahmetburakakay/urlfetcher/fetcher"
+)
+
+// envVarRe matches environment variables as dumped by env endpoints and
+// files: NAME=VALUE lines, or "NAME": values in JSON such as Spring Boot
+// Actuator's /actuator/env.
+var envVarRe = regexp.MustCompile(`(?m)^[A-Z][A-Z0-9_]{2,}=\S|"[A-Z][A-Z0-9_]{2,}"\s*:\s*["{\d]`)
+
+// exposesEnv reports whether body lists at least two environment
+// variables; one alone is as likely to be a constant in some page.
+func exposesEnv(body []byte) bool {
+	return len(envVarRe.FindAllIndex(body, 2)) == 2
+}
+
+var envExposureProbe = &hostProbe{
+	label: "ENV-EXPOSED",
+	paths: []string{
+		"/actuator/env",
+		"/env",
+		"/api/env",
+		"/.env.json",
+		"/env.json",
+		"/config/env",
+		"/__pycache__/config.cpython-311.pyc",
+		"/__pycache__/settings.cpython-311.pyc",
+	},
+	match: func(resp *http.Response, body []byte) bool {
+		return resp.StatusCode == http.StatusOK && !fetcher.IsHTML(body) && exposesEnv(body)
+	},
+}
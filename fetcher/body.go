@@ -0,0 +1,263 @@
+package fetcher
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"hash"
+	"io"
+	"io/ioutil"
+	"os"
+	"unicode"
+)
+
+// InspectLimit is how much of each response body is kept in memory for
+// detectors, HTML sniffing and the HAR file. The full body is spooled to a
+// temporary file, so saving and matching aren't limited by it.
+const InspectLimit = 10 << 20
+
+// Body is a response body written to a temporary file as it is read, with
+// only its first InspectLimit bytes held in memory.
+type Body struct {
+	file *os.File
+	// Size is the length of the whole body.
+	Size int64
+	// Sum is the SHA-256 of the whole body.
+	Sum [sha256.Size]byte
+	// Head holds the first InspectLimit bytes of the body.
+	Head []byte
+}
+
+// headWriter keeps the first limit bytes written to it and discards the
+// rest, without ever returning a short write.
+type headWriter struct {
+	buf   bytes.Buffer
+	limit int
+}
+
+func (w *headWriter) Write(p []byte) (int, error) {
+	if room := w.limit - w.buf.Len(); room > 0 {
+		if len(p) > room {
+			w.buf.Write(p[:room])
+		} else {
+			w.buf.Write(p)
+		}
+	}
+	return len(p), nil
+}
+
+// ReadBody copies r to a new temporary file. The caller must call Discard
+// once done with the result.
+func ReadBody(r io.Reader) (*Body, error) {
+	f, err := ioutil.TempFile("", "urlfetcher-body-*")
+	if err != nil {
+		return nil, err
+	}
+
+	var h hash.Hash = sha256.New()
+	head := &headWriter{limit: InspectLimit}
+
+	n, err := io.Copy(io.MultiWriter(f, h, head), r)
+	if err != nil {
+		f.Close()
+		os.Remove(f.Name())
+		return nil, err
+	}
+
+	b := &Body{file: f, Size: n, Head: head.buf.Bytes()}
+	h.Sum(b.Sum[:0])
+	return b, nil
+}
+
+// complete reports whether Head holds the whole body.
+func (b *Body) complete() bool {
+	return int64(len(b.Head)) == b.Size
+}
+
+// reader returns a reader for the whole body from the start.
+func (b *Body) reader() (*bufio.Reader, error) {
+	if _, err := b.file.Seek(0, io.SeekStart); err != nil {
+		return nil, err
+	}
+	return bufio.NewReader(b.file), nil
+}
+
+// Contains reports whether the body contains needle, reading it back from
+// disk if it didn't fit in memory.
+func (b *Body) Contains(needle []byte) (bool, error) {
+	if bytes.Contains(b.Head, needle) {
+		return true, nil
+	}
+	if b.complete() {
+		return false, nil
+	}
+
+	r, err := b.reader()
+	if err != nil {
+		return false, err
+	}
+
+	// Search chunk by chunk, carrying over enough of the previous chunk
+	// to catch matches that straddle two of them.
+	buf := make([]byte, 0, 64*1024+len(needle))
+	chunk := make([]byte, 64*1024)
+	for {
+		n, err := r.Read(chunk)
+		buf = append(buf, chunk[:n]...)
+		if bytes.Contains(buf, needle) {
+			return true, nil
+		}
+		if keep := len(needle) - 1; len(buf) > keep {
+			buf = append(buf[:0], buf[len(buf)-keep:]...)
+		}
+		if err == io.EOF {
+			return false, nil
+		}
+		if err != nil {
+			return false, err
+		}
+	}
+}
+
+// Blank reports whether the body is empty or only whitespace.
+func (b *Body) Blank() (bool, error) {
+	if len(bytes.TrimSpace(b.Head)) != 0 {
+		return false, nil
+	}
+	if b.complete() {
+		return true, nil
+	}
+
+	r, err := b.reader()
+	if err != nil {
+		return false, err
+	}
+	for {
+		c, _, err := r.ReadRune()
+		if err == io.EOF {
+			return true, nil
+		}
+		if err != nil {
+			return false, err
+		}
+		if !unicode.IsSpace(c) {
+			return false, nil
+		}
+	}
+}
+
+// Count returns the number of whitespace-separated words in the body, as
+// bytes.Fields would split it, and the number of newlines, like wc -w and
+// wc -l.
+func (b *Body) Count() (words, lines int, err error) {
+	var r io.RuneReader = bytes.NewReader(b.Head)
+	if !b.complete() {
+		r, err = b.reader()
+		if err != nil {
+			return 0, 0, err
+		}
+	}
+
+	inWord := false
+	for {
+		c, _, err := r.ReadRune()
+		if err == io.EOF {
+			return words, lines, nil
+		}
+		if err != nil {
+			return 0, 0, err
+		}
+		if c == '\n' {
+			lines++
+		}
+		if unicode.IsSpace(c) {
+			inWord = false
+		} else if !inWord {
+			inWord = true
+			words++
+		}
+	}
+}
+
+// Keep moves the body to path.
+func (b *Body) Keep(path string) error {
+	b.file.Close()
+	if err := os.Rename(b.file.Name(), path); err == nil {
+		return os.Chmod(path, 0644)
+	}
+
+	// The temporary directory may be on another filesystem.
+	src, err := os.Open(b.file.Name())
+	if err != nil {
+		return err
+	}
+	defer os.Remove(b.file.Name())
+	defer src.Close()
+
+	dst, err := os.OpenFile(path, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(dst, src); err != nil {
+		dst.Close()
+		return err
+	}
+	return dst.Close()
+}
+
+// Append adds the body to the end of path, creating it if it doesn't
+// exist. If path already has content, separator is written first.
+func (b *Body) Append(path, separator string) error {
+	r, err := b.reader()
+	if err != nil {
+		return err
+	}
+
+	dst, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	fi, err := dst.Stat()
+	if err == nil && fi.Size() > 0 {
+		_, err = io.WriteString(dst, separator)
+	}
+	if err == nil {
+		_, err = io.Copy(dst, r)
+	}
+	if err != nil {
+		dst.Close()
+		return err
+	}
+	return dst.Close()
+}
+
+// Discard removes the temporary file. It is safe to call after Keep.
+func (b *Body) Discard() {
+	b.file.Close()
+	os.Remove(b.file.Name())
+}
+
+// FileSum returns the SHA-256 of the named file, for comparing it against
+// bodies without holding either in memory.
+func FileSum(filename string) ([sha256.Size]byte, error) {
+	var sum [sha256.Size]byte
+
+	f, err := os.Open(filename)
+	if err != nil {
+		return sum, err
+	}
+	defer f.Close()
+
+	h := sha256.New()
+	_, err = io.Copy(h, f)
+	if err != nil {
+		return sum, err
+	}
+	copy(sum[:], h.Sum(nil))
+	return sum, nil
+}
+
+// Equal reports whether the body's SHA-256 is sum.
+func (b *Body) Equal(sum [sha256.Size]byte) bool {
+	return b.Sum == sum
+}
@@ -0,0 +1,134 @@
+package fetcher
+
+import (
+	"context"
+	"crypto/tls"
+	"log"
+	"net"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+// ClientOptions holds the flags that shape the HTTP client and its
+// transport.
+type ClientOptions struct {
+	// KeepAlives reuses connections between requests (--keep-alive).
+	KeepAlives      bool
+	IdleConnTimeout time.Duration
+	MaxIdleConns    int
+	// Proxy is the URL of a proxy to send requests through (--proxy).
+	Proxy string
+	// ConnectTimeout limits establishing a connection (--connect-timeout).
+	ConnectTimeout time.Duration
+	// Timeout limits each request, including reading the body (--timeout).
+	Timeout time.Duration
+	// DNSResolver is the host:port of a DNS server to use instead of the
+	// system resolver (--dns-resolver).
+	DNSResolver string
+	Hosts       HostOverrides
+	// DNSCacheTTL is how long lookups are cached for, or 0 to not cache
+	// them (--dns-cache-ttl).
+	DNSCacheTTL time.Duration
+	// Network is "tcp4" or "tcp6" to only use IPv4 or IPv6 (--ipv4,
+	// --ipv6).
+	Network string
+	H2C     bool
+	HTTP10  bool
+	// Digest answers Digest authentication challenges with these
+	// credentials.
+	Digest *Credential
+	// RawEncoding turns off Go's transparent gzip handling, which
+	// decompresses responses and drops their Content-Encoding.
+	RawEncoding bool
+	// Logger, if set, receives a line for every Hosts override used.
+	Logger *log.Logger
+}
+
+// NewDial returns the function connections are dialed with for opts,
+// which applies Network, DNSResolver, DNSCacheTTL and Hosts.
+func NewDial(opts ClientOptions) DialFunc {
+	dialer := &net.Dialer{
+		Timeout:   opts.ConnectTimeout,
+		KeepAlive: time.Second,
+	}
+	if opts.Network != "" {
+		dialer.FallbackDelay = -1
+	}
+
+	if opts.DNSResolver != "" {
+		dialer.Resolver = newResolver(opts.DNSResolver, opts.ConnectTimeout)
+	}
+
+	dial := dialer.DialContext
+	if opts.DNSCacheTTL > 0 {
+		dial = newDNSCache(dialer.Resolver, opts.DNSCacheTTL).wrap(dial)
+	}
+	if opts.Network != "" {
+		next := dial
+		dial = func(ctx context.Context, _, addr string) (net.Conn, error) {
+			return next(ctx, opts.Network, addr)
+		}
+	}
+	// Overrides are applied last so that they're checked before the DNS
+	// cache or resolver is asked.
+	if len(opts.Hosts) > 0 {
+		dial = opts.Hosts.wrap(dial, opts.Logger)
+	}
+	return dial
+}
+
+// NewClient returns a client configured by opts. It only follows
+// redirects for requests whose context comes from WithMaxRedirects.
+func NewClient(opts ClientOptions) *http.Client {
+	tr := &http.Transport{
+		MaxIdleConns:       opts.MaxIdleConns,
+		IdleConnTimeout:    opts.IdleConnTimeout,
+		DisableKeepAlives:  !opts.KeepAlives,
+		DisableCompression: opts.RawEncoding,
+		TLSClientConfig:    &tls.Config{InsecureSkipVerify: false},
+		DialContext:        NewDial(opts),
+	}
+
+	if opts.Proxy != "" {
+		if p, err := url.Parse(opts.Proxy); err == nil {
+			tr.Proxy = http.ProxyURL(p)
+		}
+	}
+
+	var rt http.RoundTripper = tr
+	if opts.H2C {
+		rt = newH2CTransport(tr)
+	}
+	if opts.HTTP10 {
+		rt = newHTTP10Transport(tr)
+	}
+	if opts.Digest != nil {
+		rt = &digestTransport{next: rt, cred: *opts.Digest}
+	}
+	rt = &redirectRecordingTransport{next: rt}
+
+	return &http.Client{
+		Transport:     rt,
+		CheckRedirect: checkRedirect,
+		Timeout:       opts.Timeout,
+	}
+}
+
+// newResolver returns a resolver that sends every DNS query to server
+// (host or host:port, port 53 by default) instead of the system resolver.
+// The pure Go resolver is used so that the OS configuration is bypassed on
+// every platform.
+func newResolver(server string, timeout time.Duration) *net.Resolver {
+	if _, _, err := net.SplitHostPort(server); err != nil {
+		server = net.JoinHostPort(server, "53")
+	}
+
+	return &net.Resolver{
+		PreferGo: true,
+		Dial: func(ctx context.Context, network, _ string) (net.Conn, error) {
+			d := net.Dialer{Timeout: timeout}
+			return d.DialContext(ctx, network, server)
+		},
+	}
+}
@@ -0,0 +1,183 @@
+package fetcher
+
+import (
+	"crypto/md5"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"net/http"
+	"strings"
+)
+
+// Credential is a username and password for Digest authentication.
+type Credential struct {
+	Username string
+	Password string
+}
+
+// digestTransport answers HTTP Digest challenges (RFC 7616). A request
+// that gets a 401 with a Digest challenge is sent again once, with
+// credentials computed from the challenge; anything else is passed
+// through untouched.
+type digestTransport struct {
+	next http.RoundTripper
+	cred Credential
+}
+
+func (t *digestTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	resp, err := t.next.RoundTrip(req)
+	if err != nil || resp.StatusCode != http.StatusUnauthorized {
+		return resp, err
+	}
+
+	challenge, ok := digestChallenge(resp)
+	if !ok || (req.Body != nil && req.GetBody == nil) {
+		return resp, nil
+	}
+
+	authorization, err := digestAuthorization(t.cred, challenge, req.Method, req.URL.RequestURI())
+	if err != nil {
+		return resp, nil
+	}
+
+	retry := req.Clone(req.Context())
+	if req.GetBody != nil {
+		retry.Body, err = req.GetBody()
+		if err != nil {
+			return resp, nil
+		}
+	}
+	retry.Header.Set("Authorization", authorization)
+
+	resp.Body.Close()
+	return t.next.RoundTrip(retry)
+}
+
+// digestChallenge returns the parameters of the first Digest challenge in
+// resp's WWW-Authenticate headers.
+func digestChallenge(resp *http.Response) (map[string]string, bool) {
+	for _, v := range resp.Header.Values("WWW-Authenticate") {
+		scheme, rest, _ := strings.Cut(strings.TrimSpace(v), " ")
+		if strings.EqualFold(scheme, "Digest") {
+			return parseAuthParams(rest), true
+		}
+	}
+	return nil, false
+}
+
+// parseAuthParams parses comma-separated name=value pairs whose values may
+// be quoted strings containing commas, such as qop="auth,auth-int".
+func parseAuthParams(s string) map[string]string {
+	params := make(map[string]string)
+	for s != "" {
+		s = strings.TrimLeft(s, " \t,")
+		name, rest, ok := strings.Cut(s, "=")
+		if !ok {
+			break
+		}
+		name = strings.ToLower(strings.TrimSpace(name))
+		rest = strings.TrimLeft(rest, " \t")
+
+		var value string
+		if strings.HasPrefix(rest, `"`) {
+			var b strings.Builder
+			i := 1
+			for ; i < len(rest) && rest[i] != '"'; i++ {
+				if rest[i] == '\\' && i+1 < len(rest) {
+					i++
+				}
+				b.WriteByte(rest[i])
+			}
+			value, s = b.String(), rest[min(i+1, len(rest)):]
+		} else {
+			value, s, _ = strings.Cut(rest, ",")
+			value = strings.TrimSpace(value)
+		}
+
+		params[name] = value
+	}
+	return params
+}
+
+// digestAuthorization computes the Authorization header value answering
+// challenge for a request of method to uri. Only the "auth" quality of
+// protection is supported, with MD5 or SHA-256 and their -sess variants.
+func digestAuthorization(cred Credential, challenge map[string]string, method, uri string) (string, error) {
+	algorithm := challenge["algorithm"]
+	if algorithm == "" {
+		algorithm = "MD5"
+	}
+
+	var newHash func() hash.Hash
+	switch strings.TrimSuffix(strings.ToUpper(algorithm), "-SESS") {
+	case "MD5":
+		newHash = md5.New
+	case "SHA-256":
+		newHash = sha256.New
+	default:
+		return "", fmt.Errorf("unsupported digest algorithm: %s", algorithm)
+	}
+	h := func(s string) string {
+		d := newHash()
+		d.Write([]byte(s))
+		return hex.EncodeToString(d.Sum(nil))
+	}
+
+	b := make([]byte, 8)
+	rand.Read(b)
+	cnonce := hex.EncodeToString(b)
+	nonce := challenge["nonce"]
+	realm := challenge["realm"]
+	const nc = "00000001"
+
+	ha1 := h(cred.Username + ":" + realm + ":" + cred.Password)
+	if strings.HasSuffix(strings.ToUpper(algorithm), "-SESS") {
+		ha1 = h(ha1 + ":" + nonce + ":" + cnonce)
+	}
+	ha2 := h(method + ":" + uri)
+
+	var qop string
+	if q, ok := challenge["qop"]; ok {
+		for _, v := range strings.Split(q, ",") {
+			if strings.TrimSpace(v) == "auth" {
+				qop = "auth"
+			}
+		}
+		if qop == "" {
+			return "", fmt.Errorf("unsupported digest qop: %s", q)
+		}
+	}
+
+	var response string
+	if qop != "" {
+		response = h(ha1 + ":" + nonce + ":" + nc + ":" + cnonce + ":" + qop + ":" + ha2)
+	} else {
+		response = h(ha1 + ":" + nonce + ":" + ha2)
+	}
+
+	fields := []string{
+		"username=" + quotedString(cred.Username),
+		"realm=" + quotedString(realm),
+		"nonce=" + quotedString(nonce),
+		"uri=" + quotedString(uri),
+		"algorithm=" + algorithm,
+		"response=" + quotedString(response),
+	}
+	if qop != "" {
+		fields = append(fields, "qop="+qop, "nc="+nc, "cnonce="+quotedString(cnonce))
+	}
+	if opaque, ok := challenge["opaque"]; ok {
+		fields = append(fields, "opaque="+quotedString(opaque))
+	}
+
+	return "Digest " + strings.Join(fields, ", "), nil
+}
+
+// quotedString returns s as an HTTP quoted-string, with a backslash
+// before each " and \ in it. Go's %q escaping isn't the same.
+func quotedString(s string) string {
+	r := strings.NewReplacer(`\`, `\\`, `"`, `\"`)
+	return `"` + r.Replace(s) + `"`
+}
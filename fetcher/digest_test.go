@@ -0,0 +1,177 @@
+package fetcher
+
+import (
+	"crypto/md5"
+	"crypto/sha256"
+	"encoding/hex"
+	"hash"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestParseAuthParams(t *testing.T) {
+	got := parseAuthParams(`realm="a \"quoted\" realm", qop="auth,auth-int", algorithm=SHA-256, nonce="n\\1",stale=false`)
+	want := map[string]string{
+		"realm":     `a "quoted" realm`,
+		"qop":       "auth,auth-int",
+		"algorithm": "SHA-256",
+		"nonce":     `n\1`,
+		"stale":     "false",
+	}
+
+	if len(got) != len(want) {
+		t.Errorf("got %d params %v, want %d", len(got), got, len(want))
+	}
+	for k, v := range want {
+		if got[k] != v {
+			t.Errorf("%s = %q, want %q", k, got[k], v)
+		}
+	}
+}
+
+func TestQuotedString(t *testing.T) {
+	for in, want := range map[string]string{
+		"plain":  `"plain"`,
+		`a"b`:    `"a\"b"`,
+		`a\b`:    `"a\\b"`,
+		"tab\té": "\"tab\té\"",
+	} {
+		if got := quotedString(in); got != want {
+			t.Errorf("quotedString(%q) = %s, want %s", in, got, want)
+		}
+		if got := parseAuthParams("x=" + quotedString(in))["x"]; got != in {
+			t.Errorf("%q doesn't survive parseAuthParams, got %q", in, got)
+		}
+	}
+}
+
+// checkDigest verifies an Authorization header the way a server would,
+// for a user with password.
+func checkDigest(t *testing.T, authorization, method, password string) map[string]string {
+	t.Helper()
+
+	scheme, rest, _ := strings.Cut(authorization, " ")
+	if scheme != "Digest" {
+		t.Fatalf("scheme is %q, want Digest", scheme)
+	}
+	p := parseAuthParams(rest)
+
+	var newHash func() hash.Hash = md5.New
+	if strings.HasPrefix(p["algorithm"], "SHA-256") {
+		newHash = sha256.New
+	}
+	h := func(s string) string {
+		d := newHash()
+		d.Write([]byte(s))
+		return hex.EncodeToString(d.Sum(nil))
+	}
+
+	ha1 := h(p["username"] + ":" + p["realm"] + ":" + password)
+	if strings.HasSuffix(p["algorithm"], "-sess") {
+		ha1 = h(ha1 + ":" + p["nonce"] + ":" + p["cnonce"])
+	}
+	ha2 := h(method + ":" + p["uri"])
+
+	want := h(ha1 + ":" + p["nonce"] + ":" + ha2)
+	if p["qop"] != "" {
+		want = h(ha1 + ":" + p["nonce"] + ":" + p["nc"] + ":" + p["cnonce"] + ":" + p["qop"] + ":" + ha2)
+	}
+	if p["response"] != want {
+		t.Errorf("response is %s, want %s", p["response"], want)
+	}
+	return p
+}
+
+func TestDigestAuthorization(t *testing.T) {
+	cred := Credential{Username: `us"er`, Password: "secret"}
+
+	tests := []struct {
+		challenge map[string]string
+		qop       string
+	}{
+		{map[string]string{"realm": "r", "nonce": "n"}, ""},
+		{map[string]string{"realm": "r", "nonce": "n", "qop": "auth"}, "auth"},
+		{map[string]string{"realm": "r", "nonce": "n", "qop": "auth-int, auth", "algorithm": "SHA-256"}, "auth"},
+		{map[string]string{"realm": "r", "nonce": "n", "qop": "auth", "algorithm": "MD5-sess"}, "auth"},
+		{map[string]string{"realm": "r", "nonce": "n", "qop": "auth", "algorithm": "SHA-256-sess", "opaque": "o"}, "auth"},
+	}
+
+	for _, tt := range tests {
+		authorization, err := digestAuthorization(cred, tt.challenge, http.MethodGet, "/a?b=1")
+		if err != nil {
+			t.Errorf("%v: %s", tt.challenge, err)
+			continue
+		}
+
+		p := checkDigest(t, authorization, http.MethodGet, cred.Password)
+		if p["username"] != cred.Username || p["uri"] != "/a?b=1" {
+			t.Errorf("%v: username %q and uri %q sent", tt.challenge, p["username"], p["uri"])
+		}
+		if p["qop"] != tt.qop {
+			t.Errorf("%v: qop is %q, want %q", tt.challenge, p["qop"], tt.qop)
+		}
+		if tt.qop != "" && (p["nc"] != "00000001" || p["cnonce"] == "") {
+			t.Errorf("%v: nc %q and cnonce %q sent", tt.challenge, p["nc"], p["cnonce"])
+		}
+		if p["opaque"] != tt.challenge["opaque"] {
+			t.Errorf("%v: opaque is %q", tt.challenge, p["opaque"])
+		}
+		if want := tt.challenge["algorithm"]; want != "" && p["algorithm"] != want {
+			t.Errorf("%v: algorithm is %q", tt.challenge, p["algorithm"])
+		}
+	}
+}
+
+func TestDigestAuthorizationUnsupported(t *testing.T) {
+	cred := Credential{Username: "u", Password: "p"}
+	for _, challenge := range []map[string]string{
+		{"nonce": "n", "algorithm": "SHA-512"},
+		{"nonce": "n", "qop": "auth-int"},
+	} {
+		if _, err := digestAuthorization(cred, challenge, http.MethodGet, "/"); err == nil {
+			t.Errorf("%v accepted", challenge)
+		}
+	}
+}
+
+func TestDigestTransport(t *testing.T) {
+	const password = "secret"
+
+	for _, algorithm := range []string{"MD5", "SHA-256"} {
+		var attempts int
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			attempts++
+			authorization := r.Header.Get("Authorization")
+			if authorization == "" {
+				w.Header().Set("WWW-Authenticate", `Digest realm="test \"realm\"", qop="auth", nonce="abc", opaque="xyz", algorithm=`+algorithm)
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+
+			p := checkDigest(t, authorization, r.Method, password)
+			if p["realm"] != `test "realm"` || p["uri"] != r.URL.RequestURI() {
+				t.Errorf("%s: realm %q and uri %q sent", algorithm, p["realm"], p["uri"])
+			}
+			if t.Failed() {
+				w.WriteHeader(http.StatusUnauthorized)
+			}
+		}))
+
+		client := &http.Client{Transport: &digestTransport{
+			next: http.DefaultTransport,
+			cred: Credential{Username: `a\b`, Password: password},
+		}}
+		resp, err := client.Post(srv.URL+"/x?y=1", "text/plain", strings.NewReader("body"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		srv.Close()
+
+		if resp.StatusCode != http.StatusOK || attempts != 2 {
+			t.Errorf("%s: got %d after %d attempts, want 200 after 2", algorithm, resp.StatusCode, attempts)
+		}
+	}
+}
@@ -0,0 +1,125 @@
+package fetcher
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DialFunc dials a connection, like net.Dialer's DialContext.
+type DialFunc func(ctx context.Context, network, addr string) (net.Conn, error)
+
+// dnsCache resolves hostnames once and reuses the result for ttl, so that
+// scans with keep-alives disabled don't pay for a lookup per connection.
+type dnsCache struct {
+	resolver *net.Resolver
+	ttl      time.Duration
+	entries  sync.Map
+}
+
+type dnsEntry struct {
+	ips     []net.IP
+	expires time.Time
+}
+
+func newDNSCache(resolver *net.Resolver, ttl time.Duration) *dnsCache {
+	if resolver == nil {
+		resolver = net.DefaultResolver
+	}
+	return &dnsCache{resolver: resolver, ttl: ttl}
+}
+
+func (c *dnsCache) lookup(ctx context.Context, host string) ([]net.IP, error) {
+	if v, ok := c.entries.Load(host); ok {
+		e := v.(dnsEntry)
+		if time.Now().Before(e.expires) {
+			return e.ips, nil
+		}
+	}
+
+	addrs, err := c.resolver.LookupIPAddr(ctx, host)
+	if err != nil {
+		return nil, err
+	}
+
+	ips := make([]net.IP, len(addrs))
+	for i, a := range addrs {
+		ips[i] = a.IP
+	}
+	c.entries.Store(host, dnsEntry{ips: ips, expires: time.Now().Add(c.ttl)})
+
+	return ips, nil
+}
+
+// wrap returns a dial function that resolves the host part of addr through
+// the cache and then dials each resulting IP in turn with dial.
+func (c *dnsCache) wrap(dial DialFunc) DialFunc {
+	return func(ctx context.Context, network, addr string) (net.Conn, error) {
+		host, port, err := net.SplitHostPort(addr)
+		if err != nil || net.ParseIP(host) != nil {
+			return dial(ctx, network, addr)
+		}
+
+		ips, err := c.lookup(ctx, host)
+		if err != nil {
+			return nil, err
+		}
+
+		var conn net.Conn
+		err = &net.AddrError{Err: "no suitable address found", Addr: host}
+		for _, ip := range ips {
+			if (network == "tcp4" && ip.To4() == nil) || (network == "tcp6" && ip.To4() != nil) {
+				continue
+			}
+			conn, err = dial(ctx, network, net.JoinHostPort(ip.String(), port))
+			if err == nil {
+				return conn, nil
+			}
+		}
+		return nil, err
+	}
+}
+
+// HostOverrides maps lowercase hostnames to the IP addresses they are
+// dialled at for --custom-dns-hosts, like /etc/hosts entries.
+type HostOverrides map[string]string
+
+// ParseHostOverrides parses --custom-dns-hosts values of the form
+// host=ip.
+func ParseHostOverrides(args []string) (HostOverrides, error) {
+	hosts := make(HostOverrides)
+	for _, arg := range args {
+		host, ip, ok := strings.Cut(arg, "=")
+		host = strings.ToLower(strings.TrimSpace(host))
+		ip = strings.TrimSpace(ip)
+		if !ok || host == "" || net.ParseIP(ip) == nil {
+			return nil, fmt.Errorf("expected host=ip, got %q", arg)
+		}
+		hosts[host] = ip
+	}
+	return hosts, nil
+}
+
+// wrap returns a dial function that dials overridden hosts at their IP
+// address without looking them up, and everything else with dial. Each
+// override used is logged to logger, if set.
+func (h HostOverrides) wrap(dial DialFunc, logger *log.Logger) DialFunc {
+	return func(ctx context.Context, network, addr string) (net.Conn, error) {
+		host, port, err := net.SplitHostPort(addr)
+		if err != nil {
+			return dial(ctx, network, addr)
+		}
+		ip, ok := h[strings.ToLower(host)]
+		if !ok {
+			return dial(ctx, network, addr)
+		}
+		if logger != nil {
+			logger.Printf("> %s resolved to %s by --custom-dns-hosts", host, ip)
+		}
+		return dial(ctx, network, net.JoinHostPort(ip, port))
+	}
+}
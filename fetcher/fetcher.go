@@ -0,0 +1,248 @@
+// Package fetcher fetches URLs concurrently and saves the responses in the
+// same layout as the urlFetcher command, for embedding in other tools. The
+// command is built on the same client, body spooling, save filter and file
+// writing as Fetch, so both save the same files for the same request.
+//
+// Only the core fetching and saving options are available in Config; the
+// detection and reporting flags of the command are not.
+package fetcher
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/time/rate"
+)
+
+// DefaultTimeout is the request timeout used when Config.Timeout is zero,
+// the same as the command's --timeout default.
+const DefaultTimeout = 10 * time.Second
+
+// Config holds the options for Fetch. The zero value sends GET requests
+// without a delay and doesn't save anything.
+type Config struct {
+	// Method is the HTTP method; it defaults to GET, or POST if Body is
+	// set (-X).
+	Method string
+	// Headers are set on every request, each as "Name: value" (-H).
+	// Lines without a colon are skipped.
+	Headers []string
+	// Body is sent with every request (-b).
+	Body string
+
+	// ClientOptions configure the client. Timeout defaults to
+	// DefaultTimeout and ConnectTimeout to Timeout.
+	ClientOptions
+	// MaxRedirects is the number of redirects to follow; the final
+	// response is the one reported and saved (--max-redirects).
+	MaxRedirects int
+	// Delay is the time between starting requests (--delay).
+	Delay time.Duration
+	// Burst is the number of requests that may start at once before
+	// Delay applies; it defaults to 1 (--burst).
+	Burst int
+
+	// Filter decides which responses are saved.
+	Filter
+	// OutputDir is the directory responses are saved in (-o).
+	OutputDir string
+	// OutputFlat saves files directly in OutputDir instead of under
+	// <host>/<path>/ (--output-flat).
+	OutputFlat bool
+	// ResponseCodeDirs saves files under a directory named after the
+	// status code (--output-response-code-dirs).
+	ResponseCodeDirs bool
+	// Hash names saved files: "sha1", the default, or "sha256" (--hash).
+	Hash string
+}
+
+// Result is the outcome of fetching one URL. If Err is set, the other
+// fields other than URL may be empty.
+type Result struct {
+	// URL is the URL of the final response, after any redirects.
+	URL        string
+	StatusCode int
+	// Body holds at most the first InspectLimit bytes of the body, which
+	// is Size bytes long.
+	Body     []byte
+	Size     int64
+	Headers  http.Header
+	Duration time.Duration
+	// SavedPath is the path of the saved .body file, or "" if the response
+	// wasn't saved.
+	SavedPath string
+	Err       error
+}
+
+// Fetch requests every URL received from urls and sends a Result for each
+// on the returned channel, which is closed once urls is closed and all
+// requests have finished. Requests are made concurrently, so results
+// arrive in the order requests finish. Cancelling ctx stops reading urls
+// and aborts requests in flight.
+func Fetch(ctx context.Context, cfg Config, urls <-chan string) <-chan Result {
+	if cfg.Timeout == 0 {
+		cfg.Timeout = DefaultTimeout
+	}
+	if cfg.ConnectTimeout == 0 {
+		cfg.ConnectTimeout = cfg.Timeout
+	}
+	if cfg.Burst < 1 {
+		cfg.Burst = 1
+	}
+	if cfg.Hash == "" {
+		cfg.Hash = "sha1"
+	}
+
+	client := NewClient(cfg.ClientOptions)
+	limiter := rate.NewLimiter(rate.Every(cfg.Delay), cfg.Burst)
+	hash, hashErr := NewHash(cfg.Hash)
+	results := make(chan Result)
+
+	go func() {
+		defer close(results)
+
+		var wg sync.WaitGroup
+		defer wg.Wait()
+
+		for {
+			var rawURL string
+			var ok bool
+			select {
+			case rawURL, ok = <-urls:
+			case <-ctx.Done():
+			}
+			if !ok || limiter.Wait(ctx) != nil {
+				return
+			}
+
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				res := Result{URL: rawURL, Err: hashErr}
+				if hashErr == nil {
+					res = fetch(ctx, client, hash, cfg, rawURL)
+				}
+				select {
+				case results <- res:
+				case <-ctx.Done():
+				}
+			}()
+		}
+	}()
+
+	return results
+}
+
+// fetch makes the request for rawURL and saves the response if cfg asks
+// for it.
+func fetch(ctx context.Context, client *http.Client, hash func([]byte) []byte, cfg Config, rawURL string) Result {
+	res := Result{URL: rawURL}
+
+	method := cfg.Method
+	if method == "" {
+		method = http.MethodGet
+	}
+	var body io.Reader
+	if cfg.Body != "" {
+		body = strings.NewReader(cfg.Body)
+		if method == http.MethodGet {
+			method = http.MethodPost
+		}
+	}
+
+	req, err := NewRequest(method, rawURL, body, cfg.Headers)
+	if err != nil {
+		res.Err = err
+		return res
+	}
+	if cfg.MaxRedirects > 0 {
+		ctx = WithMaxRedirects(ctx, cfg.MaxRedirects)
+	}
+	req = req.WithContext(ctx)
+
+	start := time.Now()
+	resp, err := client.Do(req)
+	if err != nil {
+		res.Err = err
+		return res
+	}
+	defer resp.Body.Close()
+
+	b, err := ReadBody(resp.Body)
+	res.Duration = time.Since(start)
+	if err != nil {
+		res.Err = fmt.Errorf("failed to read body: %w", err)
+		return res
+	}
+	defer b.Discard()
+
+	if len(RedirectChain(resp)) > 0 {
+		res.URL = resp.Request.URL.String()
+	}
+	res.StatusCode = resp.StatusCode
+	res.Headers = resp.Header
+	res.Body = b.Head
+	res.Size = b.Size
+
+	save, _, err := cfg.Filter.Check(resp, b, false)
+	if err != nil {
+		res.Err = fmt.Errorf("failed to read body: %w", err)
+		return res
+	}
+	if !save {
+		return res
+	}
+
+	res.SavedPath, res.Err = saveResponse(cfg, hash, method, rawURL, resp, b)
+	return res
+}
+
+// saveResponse writes <hash>.body and <hash>.headers for resp, the
+// response to a method request for rawURL, and returns the path of the
+// .body file.
+func saveResponse(cfg Config, hash func([]byte) []byte, method, rawURL string, resp *http.Response, body *Body) (string, error) {
+	dir := cfg.OutputDir
+	if cfg.ResponseCodeDirs {
+		dir = path.Join(dir, strconv.Itoa(resp.StatusCode))
+	}
+	dir = SaveDir(dir, resp.Request.URL, cfg.OutputFlat)
+	err := os.MkdirAll(dir, 0750)
+	if err != nil {
+		return "", fmt.Errorf("failed to create dir: %w", err)
+	}
+
+	// Like the command, name files after the final request of a followed
+	// redirect chain.
+	finalMethod, finalURL := method, rawURL
+	if len(RedirectChain(resp)) > 0 {
+		finalMethod, finalURL = resp.Request.Method, resp.Request.URL.String()
+	}
+	sum := RequestHash(hash, finalMethod, finalURL, cfg.Body, cfg.Headers)
+	p := path.Join(dir, fmt.Sprintf("%x.body", sum))
+	err = body.Keep(p)
+	if err != nil {
+		return "", fmt.Errorf("failed to write file contents: %w", err)
+	}
+
+	f := HeadersFile{
+		Method:   method,
+		URL:      rawURL,
+		Headers:  cfg.Headers,
+		Body:     cfg.Body,
+		Response: resp,
+	}
+	err = f.Write(path.Join(dir, fmt.Sprintf("%x.headers", sum)))
+	if err != nil {
+		return "", fmt.Errorf("failed to write file contents: %w", err)
+	}
+
+	return p, nil
+}
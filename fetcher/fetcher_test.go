@@ -0,0 +1,248 @@
+package fetcher
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestFetch(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/missing" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("X-Seen", r.Header.Get("X-Test"))
+		fmt.Fprintf(w, "%s %s", r.Method, r.URL.Path)
+	}))
+	defer srv.Close()
+
+	dir := t.TempDir()
+	cfg := Config{
+		Headers:   []string{"X-Test: yes", "not a header"},
+		OutputDir: dir,
+		Filter:    Filter{SaveStatus: []int{200}},
+	}
+	urls := make(chan string, 2)
+	urls <- srv.URL + "/a"
+	urls <- srv.URL + "/missing"
+	close(urls)
+
+	results := make(map[string]Result)
+	for res := range Fetch(context.Background(), cfg, urls) {
+		results[res.URL] = res
+	}
+	if len(results) != 2 {
+		t.Fatalf("got %d results, want 2", len(results))
+	}
+
+	res := results[srv.URL+"/a"]
+	if res.Err != nil {
+		t.Fatal(res.Err)
+	}
+	if res.StatusCode != 200 || string(res.Body) != "GET /a" {
+		t.Errorf("got %d %q, want 200 \"GET /a\"", res.StatusCode, res.Body)
+	}
+	if got := res.Headers.Get("X-Seen"); got != "yes" {
+		t.Errorf("server saw X-Test %q, want \"yes\"", got)
+	}
+	if want := filepath.Join(dir, "127.0.0.1", "a"); filepath.Dir(res.SavedPath) != want {
+		t.Errorf("saved to %s, want a file in %s", res.SavedPath, want)
+	}
+
+	body, err := os.ReadFile(res.SavedPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(body) != "GET /a" {
+		t.Errorf("saved body is %q", body)
+	}
+	headers, err := os.ReadFile(strings.TrimSuffix(res.SavedPath, ".body") + ".headers")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"GET " + srv.URL + "/a\n", "> X-Test: yes\n", "< HTTP/1.1 200 OK\n", "< X-Seen: yes\n"} {
+		if !strings.Contains(string(headers), want) {
+			t.Errorf(".headers file doesn't contain %q:\n%s", want, headers)
+		}
+	}
+
+	res = results[srv.URL+"/missing"]
+	if res.Err != nil {
+		t.Fatal(res.Err)
+	}
+	if res.StatusCode != 404 || res.SavedPath != "" {
+		t.Errorf("got %d saved to %q, want an unsaved 404", res.StatusCode, res.SavedPath)
+	}
+}
+
+func TestFetchRedirects(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/old" {
+			http.Redirect(w, r, "/new", http.StatusFound)
+			return
+		}
+		fmt.Fprint(w, "moved")
+	}))
+	defer srv.Close()
+
+	for _, tt := range []struct {
+		maxRedirects int
+		status       int
+	}{
+		{0, http.StatusFound},
+		{1, http.StatusOK},
+	} {
+		urls := make(chan string, 1)
+		urls <- srv.URL + "/old"
+		close(urls)
+
+		cfg := Config{MaxRedirects: tt.maxRedirects, Filter: Filter{Save: true}, OutputDir: t.TempDir(), OutputFlat: true}
+		for res := range Fetch(context.Background(), cfg, urls) {
+			if res.Err != nil {
+				t.Fatal(res.Err)
+			}
+			if res.StatusCode != tt.status {
+				t.Errorf("MaxRedirects %d: got %d, want %d", tt.maxRedirects, res.StatusCode, tt.status)
+			}
+			if tt.maxRedirects == 0 {
+				continue
+			}
+
+			headers, err := os.ReadFile(strings.TrimSuffix(res.SavedPath, ".body") + ".headers")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !strings.Contains(string(headers), "< HTTP/1.1 302 Found\n< Location: /new\n") {
+				t.Errorf(".headers file doesn't record the redirect:\n%s", headers)
+			}
+
+			// Files are named after the final request, as the command
+			// names them.
+			hash, _ := NewHash("sha1")
+			want := fmt.Sprintf("%x.body", RequestHash(hash, http.MethodGet, srv.URL+"/new", "", nil))
+			if filepath.Base(res.SavedPath) != want {
+				t.Errorf("saved to %s, want %s", filepath.Base(res.SavedPath), want)
+			}
+		}
+	}
+}
+
+func TestFetchSaveFilters(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/vnd.api+json")
+		fmt.Fprint(w, r.URL.Query().Get("b"))
+	}))
+	defer srv.Close()
+
+	tests := []struct {
+		filter Filter
+		body   string
+		saved  bool
+	}{
+		{Filter{Save: true}, "  \n", true},
+		{Filter{Save: true, IgnoreEmpty: true}, "  \n", false},
+		{Filter{Save: true, MinSize: 5}, "abc", false},
+		{Filter{Save: true, MaxSize: 2}, "abc", false},
+		{Filter{Save: true, MaxSize: 2, Match: "b"}, "abc", true},
+		{Filter{Match: "z"}, "abc", false},
+		{Filter{Save: true, IgnoreTypes: []string{"json"}}, "abc", false},
+		{Filter{Save: true, IgnoreTypes: []string{"text/html"}}, "<HTML>", false},
+		{Filter{Save: true, ContentType: "JSON"}, "abc", true},
+		{Filter{Save: true, ContentType: "xml"}, "abc", false},
+	}
+
+	for _, tt := range tests {
+		cfg := Config{Filter: tt.filter, OutputDir: t.TempDir()}
+		urls := make(chan string, 1)
+		urls <- srv.URL + "/?b=" + url.QueryEscape(tt.body)
+		close(urls)
+
+		for res := range Fetch(context.Background(), cfg, urls) {
+			if res.Err != nil {
+				t.Fatal(res.Err)
+			}
+			if saved := res.SavedPath != ""; saved != tt.saved {
+				t.Errorf("%+v: body %q saved = %t, want %t", tt.filter, tt.body, saved, tt.saved)
+			}
+		}
+	}
+}
+
+func TestFetchDelay(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer srv.Close()
+
+	urls := make(chan string, 3)
+	for i := 0; i < 3; i++ {
+		urls <- srv.URL
+	}
+	close(urls)
+
+	start := time.Now()
+	for res := range Fetch(context.Background(), Config{Delay: 100 * time.Millisecond}, urls) {
+		if res.Err != nil {
+			t.Fatal(res.Err)
+		}
+	}
+	if elapsed := time.Since(start); elapsed < 200*time.Millisecond {
+		t.Errorf("3 requests 100ms apart took %s", elapsed)
+	}
+}
+
+func TestFetchCancel(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	urls := make(chan string)
+	for res := range Fetch(ctx, Config{}, urls) {
+		t.Errorf("got a result for %s after cancelling", res.URL)
+	}
+}
+
+func TestNewRequest(t *testing.T) {
+	req, err := NewRequest(http.MethodGet, "https://example.com/", nil, []string{
+		"X-A: 1",
+		"no colon",
+		"X-A:  2 ",
+		"X-B:",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got := req.Header.Values("X-A"); len(got) != 1 || got[0] != "2" {
+		t.Errorf("X-A is %q, want [\"2\"]", got)
+	}
+	if _, ok := req.Header["X-B"]; !ok {
+		t.Error("empty X-B header not set")
+	}
+	if len(req.Header) != 2 {
+		t.Errorf("got headers %v, want only X-A and X-B", req.Header)
+	}
+
+	if _, err := NewRequest(http.MethodGet, "://", nil, nil); err == nil {
+		t.Error("invalid URL accepted")
+	}
+}
+
+func TestSaveDir(t *testing.T) {
+	u, err := url.Parse("https://Example.com:8443/a/b c/%3Cx%3E.php?q=1")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got, want := SaveDir("out", u, false), "out/Example.com/a/b-c/-x-.php"; got != want {
+		t.Errorf("SaveDir = %q, want %q", got, want)
+	}
+	if got := SaveDir("out", u, true); got != "out" {
+		t.Errorf("flat SaveDir = %q, want \"out\"", got)
+	}
+}
@@ -0,0 +1,97 @@
+package fetcher
+
+import (
+	"net/http"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+var htmlRe = regexp.MustCompile(`(?i)<html`)
+
+// IsHTML reports whether body looks like an HTML document, whatever its
+// Content-Type says.
+func IsHTML(body []byte) bool {
+	return htmlRe.Match(body)
+}
+
+// Filter decides which responses are saved.
+type Filter struct {
+	// Save saves every response (-S).
+	Save bool
+	// SaveStatus saves responses with any of these status codes (-s).
+	SaveStatus []int
+	// IgnoreTypes doesn't save responses whose Content-Type contains any
+	// of these lowercase types (--ignore-content-type). text/html also
+	// matches bodies that look like HTML.
+	IgnoreTypes []string
+	// IgnoreEmpty doesn't save bodies that are empty or only whitespace
+	// (--ignore-empty).
+	IgnoreEmpty bool
+	// MinSize and MaxSize, if non-zero, don't save bodies smaller or
+	// larger than them (--min-size, --max-size).
+	MinSize, MaxSize int64
+	// Match saves responses whose body contains this string, whatever
+	// the filters above say (-M).
+	Match string
+	// ContentType only saves responses whose Content-Type contains it,
+	// ignoring case, whatever else says to save them (--content-type).
+	ContentType string
+}
+
+// Check reports whether resp, with body, should be saved, and whether
+// body contains Match. force saves it unless ContentType rules it out. An
+// error reading the body back is returned along with the decision made
+// without it.
+func (f Filter) Check(resp *http.Response, body *Body, force bool) (save, matched bool, err error) {
+	save = f.Save || slices.Contains(f.SaveStatus, resp.StatusCode)
+
+	if len(f.IgnoreTypes) > 0 {
+		save = save && !hasContentType(resp, body.Head, f.IgnoreTypes)
+	}
+
+	if f.IgnoreEmpty && save {
+		var blank bool
+		blank, err = body.Blank()
+		save = !blank
+	}
+
+	if body.Size < f.MinSize || (f.MaxSize > 0 && body.Size > f.MaxSize) {
+		save = false
+	}
+
+	if f.Match != "" {
+		var merr error
+		matched, merr = body.Contains([]byte(f.Match))
+		if merr != nil {
+			err = merr
+		}
+		save = save || matched
+	}
+
+	save = (save || force) && f.TypeWanted(resp)
+	return save, matched, err
+}
+
+// TypeWanted reports whether resp's Content-Type contains ContentType,
+// ignoring case, so that "json" also matches application/vnd.api+json. It
+// is always true if ContentType isn't set.
+func (f Filter) TypeWanted(resp *http.Response) bool {
+	return f.ContentType == "" || strings.Contains(strings.ToLower(resp.Header.Get("Content-Type")), strings.ToLower(f.ContentType))
+}
+
+// hasContentType reports whether resp's Content-Type contains any of types,
+// which must be lowercase. text/html also matches bodies that look like
+// HTML, whatever their Content-Type.
+func hasContentType(resp *http.Response, body []byte, types []string) bool {
+	ct := strings.ToLower(resp.Header.Get("Content-Type"))
+	for _, t := range types {
+		if strings.Contains(ct, t) {
+			return true
+		}
+		if t == "text/html" && IsHTML(body) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,42 @@
+package fetcher
+
+import (
+	"context"
+	"crypto/tls"
+	"net"
+	"net/http"
+
+	"golang.org/x/net/http2"
+)
+
+// h2cTransport sends http:// requests as HTTP/2 with prior knowledge (h2c)
+// and everything else through the regular transport, which negotiates
+// HTTP/2 over TLS with ALPN.
+type h2cTransport struct {
+	tls *http.Transport
+	h2c *http2.Transport
+}
+
+func newH2CTransport(tr *http.Transport) *h2cTransport {
+	// ConfigureTransport only fails if tr already speaks HTTP/2, and a
+	// transport with a custom dialer and TLS config doesn't by default.
+	_ = http2.ConfigureTransport(tr)
+
+	dial := tr.DialContext
+	return &h2cTransport{
+		tls: tr,
+		h2c: &http2.Transport{
+			AllowHTTP: true,
+			DialTLSContext: func(ctx context.Context, network, addr string, _ *tls.Config) (net.Conn, error) {
+				return dial(ctx, network, addr)
+			},
+		},
+	}
+}
+
+func (t *h2cTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.URL.Scheme == "http" {
+		return t.h2c.RoundTrip(req)
+	}
+	return t.tls.RoundTrip(req)
+}
@@ -0,0 +1,38 @@
+package fetcher
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"golang.org/x/net/http2"
+	"golang.org/x/net/http2/h2c"
+)
+
+func TestH2CTransport(t *testing.T) {
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, "%s tls=%t", r.Proto, r.TLS != nil)
+	})
+	srv := httptest.NewServer(h2c.NewHandler(handler, &http2.Server{}))
+	defer srv.Close()
+
+	client := NewClient(ClientOptions{H2C: true})
+	resp, err := client.Get(srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if resp.ProtoMajor != 2 {
+		t.Errorf("response protocol is %s, want HTTP/2.0", resp.Proto)
+	}
+	if got, want := string(body), "HTTP/2.0 tls=false"; got != want {
+		t.Errorf("server saw %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,112 @@
+package fetcher
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/tls"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strconv"
+)
+
+// http10Transport sends every request as HTTP/1.0 over a new connection,
+// for --http1.0. net/http always speaks HTTP/1.1 whatever req.Proto says,
+// so the request is written by hand. HTTP/1.0 has no chunked encoding, so
+// request bodies are read into memory to send a Content-Length.
+type http10Transport struct {
+	dial      DialFunc
+	tlsConfig *tls.Config
+}
+
+func newHTTP10Transport(tr *http.Transport) *http10Transport {
+	return &http10Transport{dial: tr.DialContext, tlsConfig: tr.TLSClientConfig}
+}
+
+func (t *http10Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	var body []byte
+	if req.Body != nil {
+		var err error
+		body, err = io.ReadAll(req.Body)
+		req.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	ctx := req.Context()
+	addr := req.URL.Host
+	if req.URL.Port() == "" {
+		port := "80"
+		if req.URL.Scheme == "https" {
+			port = "443"
+		}
+		addr = net.JoinHostPort(req.URL.Hostname(), port)
+	}
+
+	conn, err := t.dial(ctx, "tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+	if req.URL.Scheme == "https" {
+		cfg := t.tlsConfig.Clone()
+		cfg.ServerName = req.URL.Hostname()
+		tc := tls.Client(conn, cfg)
+		if err := tc.HandshakeContext(ctx); err != nil {
+			conn.Close()
+			return nil, err
+		}
+		conn = tc
+	}
+	stop := context.AfterFunc(ctx, func() { conn.Close() })
+
+	host := req.Host
+	if host == "" {
+		host = req.URL.Host
+	}
+	header := req.Header.Clone()
+	header.Set("Connection", "close")
+	if req.Body != nil {
+		header.Set("Content-Length", strconv.Itoa(len(body)))
+	}
+
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "%s %s HTTP/1.0\r\n", req.Method, RequestTarget(req.URL))
+	b.WriteString("Host: " + host + "\r\n")
+	header.Write(&b)
+	b.WriteString("\r\n")
+	b.Write(body)
+
+	_, err = conn.Write(b.Bytes())
+	if err == nil {
+		var resp *http.Response
+		resp, err = http.ReadResponse(bufio.NewReader(conn), req)
+		if err == nil {
+			resp.Body = &connBody{ReadCloser: resp.Body, conn: conn, stop: stop}
+			return resp, nil
+		}
+	}
+
+	stop()
+	conn.Close()
+	if ctx.Err() != nil {
+		return nil, ctx.Err()
+	}
+	return nil, err
+}
+
+// connBody closes the connection a response was read from along with its
+// body.
+type connBody struct {
+	io.ReadCloser
+	conn net.Conn
+	stop func() bool
+}
+
+func (b *connBody) Close() error {
+	b.stop()
+	b.conn.Close()
+	return b.ReadCloser.Close()
+}
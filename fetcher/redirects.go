@@ -0,0 +1,95 @@
+package fetcher
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+)
+
+// maxRedirectsKey is the context key for the --max-redirects limit.
+type maxRedirectsKey struct{}
+
+// WithMaxRedirects returns a copy of ctx that makes clients from NewClient
+// follow up to n redirects for requests made with it. Without it, no
+// redirects are followed, so that detectors always see the redirect
+// itself.
+func WithMaxRedirects(ctx context.Context, n int) context.Context {
+	return context.WithValue(ctx, maxRedirectsKey{}, n)
+}
+
+// checkRedirect follows redirects up to the limit set by WithMaxRedirects.
+func checkRedirect(req *http.Request, via []*http.Request) error {
+	if n, _ := req.Context().Value(maxRedirectsKey{}).(int); len(via) <= n {
+		return nil
+	}
+	return http.ErrUseLastResponse
+}
+
+// RedirectChain returns the redirect responses that were followed to get
+// resp, in order.
+func RedirectChain(resp *http.Response) []*http.Response {
+	var chain []*http.Response
+	for hop := resp.Request.Response; hop != nil; hop = hop.Request.Response {
+		chain = append([]*http.Response{hop}, chain...)
+	}
+	return chain
+}
+
+// redirectBodiesKey is the context key for the *RedirectBodies a request
+// records the bodies of followed redirects into for --save-redirect-chain.
+type redirectBodiesKey struct{}
+
+// RedirectBodies holds the bodies of the redirect responses of one
+// request, in the order they were received. The client closes a
+// redirect's body before following it, so they have to be read as they
+// arrive.
+type RedirectBodies struct {
+	Bodies [][]byte
+}
+
+// WithRedirectBodies returns a copy of ctx that makes clients from
+// NewClient record the bodies of the redirects followed for requests made
+// with it into rec.
+func WithRedirectBodies(ctx context.Context, rec *RedirectBodies) context.Context {
+	return context.WithValue(ctx, redirectBodiesKey{}, rec)
+}
+
+// redirectRecordingTransport reads the body of every redirect response to
+// a request carrying a redirectBodiesKey into its RedirectBodies, and
+// hands the client a copy to close.
+type redirectRecordingTransport struct {
+	next http.RoundTripper
+}
+
+func (t *redirectRecordingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	resp, err := t.next.RoundTrip(req)
+	if err != nil {
+		return resp, err
+	}
+
+	rec, ok := req.Context().Value(redirectBodiesKey{}).(*RedirectBodies)
+	if !ok || !isRedirect(resp) {
+		return resp, nil
+	}
+
+	b, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	resp.Body = io.NopCloser(bytes.NewReader(b))
+	rec.Bodies = append(rec.Bodies, b)
+
+	return resp, nil
+}
+
+// isRedirect reports whether the client would follow resp.
+func isRedirect(resp *http.Response) bool {
+	switch resp.StatusCode {
+	case http.StatusMovedPermanently, http.StatusFound, http.StatusSeeOther,
+		http.StatusTemporaryRedirect, http.StatusPermanentRedirect:
+		return resp.Header.Get("Location") != ""
+	}
+	return false
+}
@@ -0,0 +1,150 @@
+package fetcher
+
+import (
+	"crypto/sha1"
+	"crypto/sha256"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// NewRequest creates a request with headers, each "Name: value", set on
+// it. Lines without a colon are skipped, and a later header replaces an
+// earlier one of the same name.
+func NewRequest(method, rawURL string, body io.Reader, headers []string) (*http.Request, error) {
+	req, err := http.NewRequest(method, rawURL, body)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, h := range headers {
+		parts := strings.SplitN(h, ":", 2)
+		if len(parts) != 2 {
+			continue
+		}
+		req.Header.Set(parts[0], strings.TrimSpace(parts[1]))
+	}
+
+	return req, nil
+}
+
+// RequestTarget returns the path and query of u as sent on the request
+// line.
+func RequestTarget(u *url.URL) string {
+	target := u.EscapedPath()
+	if target == "" {
+		target = "/"
+	}
+	if u.RawQuery != "" {
+		target += "?" + u.RawQuery
+	}
+	return target
+}
+
+var unsafePathChars = regexp.MustCompile(`[^a-zA-Z0-9/._-]+`)
+
+// NormalisePath returns u's path with every run of characters that aren't
+// safe in file names replaced by "-", for use as a save directory.
+func NormalisePath(u *url.URL) string {
+	return unsafePathChars.ReplaceAllString(u.Path, "-")
+}
+
+// SaveDir returns the directory the response for u is saved in under dir:
+// <dir>/<host>/<path>/, or dir itself if flat is set.
+func SaveDir(dir string, u *url.URL, flat bool) string {
+	if flat {
+		return dir
+	}
+	return path.Join(dir, u.Hostname(), NormalisePath(u))
+}
+
+// NewHash returns the hash function saved files are named with for
+// --hash: "sha1" or "sha256".
+func NewHash(name string) (func([]byte) []byte, error) {
+	switch name {
+	case "sha1":
+		return func(b []byte) []byte {
+			sum := sha1.Sum(b)
+			return sum[:]
+		}, nil
+	case "sha256":
+		return func(b []byte) []byte {
+			sum := sha256.Sum256(b)
+			return sum[:]
+		}, nil
+	default:
+		return nil, fmt.Errorf("unknown hash: %s", name)
+	}
+}
+
+// RequestHash returns the hash the files saved for a request are named
+// after, computed with hash over its method, URL, body and headers. When
+// redirects were followed, method and url are those of the final request.
+func RequestHash(hash func([]byte) []byte, method, url, body string, headers []string) []byte {
+	return hash([]byte(method + url + body + strings.Join(headers, ", ")))
+}
+
+// HeadersFile is the content of a .headers file: the request that was
+// sent and the response it got.
+type HeadersFile struct {
+	Method string
+	URL    string
+	// Notes are written under the request line, each after "# ".
+	Notes   []string
+	Headers []string
+	Body    string
+	// Response is the final response. The status line and Location of
+	// each redirect followed to get it are written before its headers.
+	Response *http.Response
+}
+
+// Write writes f to filename.
+func (f HeadersFile) Write(filename string) error {
+	var buf strings.Builder
+	buf.WriteString(fmt.Sprintf("%s %s\n", f.Method, f.URL))
+	for _, n := range f.Notes {
+		buf.WriteString(fmt.Sprintf("# %s\n", n))
+	}
+	buf.WriteRune('\n')
+	for _, h := range f.Headers {
+		buf.WriteString(fmt.Sprintf("> %s\n", h))
+	}
+	buf.WriteRune('\n')
+
+	if f.Body != "" {
+		buf.WriteString(f.Body)
+		buf.WriteString("\n\n")
+	}
+
+	for _, hop := range RedirectChain(f.Response) {
+		buf.WriteString(fmt.Sprintf("< %s %s\n", hop.Proto, hop.Status))
+		buf.WriteString(fmt.Sprintf("< Location: %s\n\n", hop.Header.Get("Location")))
+	}
+	WriteResponseHeaders(&buf, f.Response)
+
+	return os.WriteFile(filename, []byte(buf.String()), 0644)
+}
+
+// WriteResponseHeaders writes resp's status line and headers to buf in the
+// .headers file format.
+func WriteResponseHeaders(buf *strings.Builder, resp *http.Response) {
+	buf.WriteString(fmt.Sprintf("< %s %s\n", resp.Proto, resp.Status))
+	// Go doesn't keep the order headers arrived in, so sort them to make
+	// .headers files from different runs comparable.
+	names := make([]string, 0, len(resp.Header))
+	for k := range resp.Header {
+		names = append(names, k)
+	}
+	sort.Strings(names)
+	for _, k := range names {
+		for _, v := range resp.Header[k] {
+			buf.WriteString(fmt.Sprintf("< %s: %s\n", k, v))
+		}
+	}
+}
@@ -0,0 +1,75 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// formBoundary is fixed rather than random so that the Content-Type header,
+// and with it the saved response filenames, are stable between runs.
+const formBoundary = "urlfetcher-form-boundary-7d1f4e2a9c"
+
+// buildMultipartBody encodes "name=value" fields and "name=@/path/file"
+// file fields as a multipart/form-data body, returning the body and its
+// Content-Type.
+func buildMultipartBody(fields, files []string) (string, string, error) {
+	var buf bytes.Buffer
+	w := multipart.NewWriter(&buf)
+
+	err := w.SetBoundary(formBoundary)
+	if err != nil {
+		return "", "", err
+	}
+
+	for _, f := range fields {
+		parts := strings.SplitN(f, "=", 2)
+		if len(parts) != 2 {
+			return "", "", fmt.Errorf("invalid form field %q, expected name=value", f)
+		}
+
+		err = w.WriteField(parts[0], parts[1])
+		if err != nil {
+			return "", "", err
+		}
+	}
+
+	for _, f := range files {
+		parts := strings.SplitN(f, "=", 2)
+		if len(parts) != 2 || !strings.HasPrefix(parts[1], "@") {
+			return "", "", fmt.Errorf("invalid form file %q, expected name=@/path/to/file", f)
+		}
+
+		err = addFormFile(w, parts[0], strings.TrimPrefix(parts[1], "@"))
+		if err != nil {
+			return "", "", err
+		}
+	}
+
+	err = w.Close()
+	if err != nil {
+		return "", "", err
+	}
+
+	return buf.String(), w.FormDataContentType(), nil
+}
+
+func addFormFile(w *multipart.Writer, field, filename string) error {
+	f, err := os.Open(filename)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	part, err := w.CreateFormFile(field, filepath.Base(filename))
+	if err != nil {
+		return err
+	}
+
+	_, err = io.Copy(part, f)
+	return err
+}
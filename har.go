@@ -0,0 +1,233 @@
+package main
+
+import (
+	"bufio"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptrace"
+	"os"
+	"sync"
+	"time"
+)
+
+// The har* types are a minimal subset of the HTTP Archive 1.2 format; see
+// http://www.softwareishard.com/blog/har-12-spec/ for the full spec.
+
+type harEntry struct {
+	StartedDateTime string      `json:"startedDateTime"`
+	Time            float64     `json:"time"`
+	Request         harRequest  `json:"request"`
+	Response        harResponse `json:"response"`
+	Cache           struct{}    `json:"cache"`
+	Timings         harTimings  `json:"timings"`
+}
+
+type harRequest struct {
+	Method      string         `json:"method"`
+	URL         string         `json:"url"`
+	HTTPVersion string         `json:"httpVersion"`
+	Cookies     []harNameValue `json:"cookies"`
+	Headers     []harNameValue `json:"headers"`
+	QueryString []harNameValue `json:"queryString"`
+	PostData    *harPostData   `json:"postData,omitempty"`
+	HeadersSize int            `json:"headersSize"`
+	BodySize    int            `json:"bodySize"`
+}
+
+type harPostData struct {
+	MimeType string `json:"mimeType"`
+	Text     string `json:"text"`
+}
+
+type harResponse struct {
+	Status      int            `json:"status"`
+	StatusText  string         `json:"statusText"`
+	HTTPVersion string         `json:"httpVersion"`
+	Cookies     []harNameValue `json:"cookies"`
+	Headers     []harNameValue `json:"headers"`
+	Content     harContent     `json:"content"`
+	RedirectURL string         `json:"redirectURL"`
+	HeadersSize int            `json:"headersSize"`
+	BodySize    int64          `json:"bodySize"`
+}
+
+type harContent struct {
+	Size     int64  `json:"size"`
+	MimeType string `json:"mimeType"`
+	Text     string `json:"text"`
+	Encoding string `json:"encoding"`
+	Comment  string `json:"comment,omitempty"`
+}
+
+type harNameValue struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+type harTimings struct {
+	Send    float64 `json:"send"`
+	Wait    float64 `json:"wait"`
+	Receive float64 `json:"receive"`
+}
+
+// requestTimer records the points in a request's lifetime needed for HAR
+// timings. Attach it to a request with Trace, and call Done once the
+// response body has been read.
+type requestTimer struct {
+	start        time.Time
+	wroteRequest time.Time
+	firstByte    time.Time
+	done         time.Time
+}
+
+func (t *requestTimer) Trace(req *http.Request) *http.Request {
+	t.start = time.Now()
+	trace := &httptrace.ClientTrace{
+		WroteRequest:         func(httptrace.WroteRequestInfo) { t.wroteRequest = time.Now() },
+		GotFirstResponseByte: func() { t.firstByte = time.Now() },
+	}
+	return req.WithContext(httptrace.WithClientTrace(req.Context(), trace))
+}
+
+func (t *requestTimer) Done() {
+	t.done = time.Now()
+}
+
+func (t *requestTimer) Elapsed() time.Duration {
+	return t.done.Sub(t.start)
+}
+
+func (t *requestTimer) timings() harTimings {
+	ms := func(d time.Duration) float64 {
+		if d < 0 {
+			return -1
+		}
+		return float64(d) / float64(time.Millisecond)
+	}
+
+	wrote, first := t.wroteRequest, t.firstByte
+	if wrote.IsZero() {
+		wrote = t.start
+	}
+	if first.IsZero() {
+		first = wrote
+	}
+
+	return harTimings{
+		Send:    ms(wrote.Sub(t.start)),
+		Wait:    ms(first.Sub(wrote)),
+		Receive: ms(t.done.Sub(first)),
+	}
+}
+
+// harWriter writes HAR entries to a file as requests complete, so that
+// response bodies aren't held in memory for the whole run. Entries appear
+// in the order their requests finished. It is safe for concurrent use.
+type harWriter struct {
+	mu sync.Mutex
+	f  *os.File
+	w  *bufio.Writer
+	n  int
+}
+
+// createHAR creates filename and writes the start of the HAR log to it.
+func createHAR(filename string) (*harWriter, error) {
+	f, err := os.Create(filename)
+	if err != nil {
+		return nil, err
+	}
+	w := bufio.NewWriter(f)
+	fmt.Fprint(w, `{"log":{"version":"1.2","creator":{"name":"urlfetcher","version":"1.0"},"entries":[`)
+	return &harWriter{f: f, w: w}, nil
+}
+
+// Add writes the entry for a request. body holds at most the first
+// fetcher.InspectLimit bytes of the response body, which is size bytes long.
+// Write errors are reported by Close.
+func (h *harWriter) Add(req *http.Request, reqBody string, resp *http.Response, body []byte, size int64, t *requestTimer) {
+	e := harEntry{
+		StartedDateTime: t.start.Format(time.RFC3339Nano),
+		Time:            float64(t.Elapsed()) / float64(time.Millisecond),
+		Request: harRequest{
+			Method:      req.Method,
+			URL:         req.URL.String(),
+			HTTPVersion: req.Proto,
+			Cookies:     []harNameValue{},
+			Headers:     harHeaders(req.Header),
+			QueryString: []harNameValue{},
+			HeadersSize: -1,
+			BodySize:    len(reqBody),
+		},
+		Response: harResponse{
+			Status:      resp.StatusCode,
+			StatusText:  http.StatusText(resp.StatusCode),
+			HTTPVersion: resp.Proto,
+			Cookies:     []harNameValue{},
+			Headers:     harHeaders(resp.Header),
+			Content: harContent{
+				Size:     size,
+				MimeType: resp.Header.Get("Content-Type"),
+				Text:     base64.StdEncoding.EncodeToString(body),
+				Encoding: "base64",
+			},
+			RedirectURL: resp.Header.Get("Location"),
+			HeadersSize: -1,
+			BodySize:    size,
+		},
+		Timings: t.timings(),
+	}
+	if int64(len(body)) < size {
+		e.Response.Content.Comment = fmt.Sprintf("text holds the first %d bytes", len(body))
+	}
+
+	for k, vs := range req.URL.Query() {
+		for _, v := range vs {
+			e.Request.QueryString = append(e.Request.QueryString, harNameValue{Name: k, Value: v})
+		}
+	}
+
+	if reqBody != "" {
+		e.Request.PostData = &harPostData{
+			MimeType: req.Header.Get("Content-Type"),
+			Text:     reqBody,
+		}
+	}
+
+	b, err := json.Marshal(e)
+	if err != nil {
+		return
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.n > 0 {
+		h.w.WriteByte(',')
+	}
+	h.w.Write(b)
+	h.n++
+}
+
+// Close ends the HAR log and closes the file.
+func (h *harWriter) Close() error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	fmt.Fprintln(h.w, "]}}")
+	err := h.w.Flush()
+	if cerr := h.f.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
+
+func harHeaders(h http.Header) []harNameValue {
+	out := []harNameValue{}
+	for k, vs := range h {
+		for _, v := range vs {
+			out = append(out, harNameValue{Name: k, Value: v})
+		}
+	}
+	return out
+}
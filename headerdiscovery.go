@@ -0,0 +1,93 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+)
+
+// influentialHeaders are non-standard request headers, with a value each,
+// that applications and the proxies in front of them are known to act on.
+var influentialHeaders = []struct {
+	name  string
+	value string
+}{
+	{"X-Internal", "true"},
+	{"X-Internal-Request", "true"},
+	{"X-Admin", "true"},
+	{"X-Is-Admin", "true"},
+	{"X-Role", "admin"},
+	{"X-User-Role", "admin"},
+	{"X-User", "admin"},
+	{"X-Username", "admin"},
+	{"X-User-Id", "1"},
+	{"X-Auth-User", "admin"},
+	{"X-Debug", "1"},
+	{"X-Debug-Mode", "true"},
+	{"X-Dev", "true"},
+	{"X-Developer", "true"},
+	{"X-Test", "true"},
+	{"X-Trace", "true"},
+	{"X-Verbose", "true"},
+	{"X-Env", "dev"},
+	{"X-Environment", "development"},
+	{"X-Staging", "true"},
+	{"X-Beta", "true"},
+	{"X-Preview", "true"},
+	{"X-Feature-Flag", "true"},
+	{"X-Override", "true"},
+	{"X-Bypass", "true"},
+	{"X-Cache-Bypass", "1"},
+	{"X-Original-URL", "/admin"},
+	{"X-Rewrite-URL", "/admin"},
+	{"X-Originating-URL", "/admin"},
+	{"X-Forwarded-For", "127.0.0.1"},
+	{"X-Real-IP", "127.0.0.1"},
+	{"X-Client-IP", "127.0.0.1"},
+	{"X-Remote-IP", "127.0.0.1"},
+	{"X-Remote-Addr", "127.0.0.1"},
+	{"X-Originating-IP", "127.0.0.1"},
+	{"X-Cluster-Client-IP", "127.0.0.1"},
+	{"X-Custom-IP-Authorization", "127.0.0.1"},
+	{"True-Client-IP", "127.0.0.1"},
+	{"Forwarded", "for=127.0.0.1"},
+	{"X-Host", "localhost"},
+	{"X-Forwarded-Host", "localhost"},
+	{"X-Forwarded-Server", "localhost"},
+	{"X-Forwarded-Proto", "http"},
+	{"X-Forwarded-Scheme", "http"},
+	{"X-Forwarded-Port", "8080"},
+	{"X-HTTP-Method-Override", "PUT"},
+	{"X-HTTP-Method", "PUT"},
+	{"X-Method-Override", "PUT"},
+	{"X-Api-Version", "2"},
+	{"Api-Version", "2"},
+	{"X-Requested-With", "XMLHttpRequest"},
+}
+
+// discoverHeaders resends r once with each of influentialHeaders and
+// reports headers whose response differs from the baseline. The baseline is
+// fetched a second time first, and URLs whose responses vary on their own
+// are skipped.
+func discoverHeaders(client *http.Client, r request, headers headerArgs, baseStatus int, baseBody []byte) {
+	resp, body, err := probe(client, r.method, r.url, r.body, headers)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "request failed: %s\n", err)
+		return
+	}
+	if responsesDiffer(baseStatus, baseBody, resp.StatusCode, body) {
+		return
+	}
+
+	for _, h := range influentialHeaders {
+		resp, body, err := probe(client, r.method, r.url, r.body, withHeader(headers, h.name, h.value))
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "request failed: %s\n", err)
+			continue
+		}
+
+		if responsesDiffer(baseStatus, baseBody, resp.StatusCode, body) {
+			fmt.Printf("HEADER-SENSITIVE:%s %s (%d -> %d)\n", h.name, r.url, baseStatus, resp.StatusCode)
+		}
+	}
+}
@@ -0,0 +1,47 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+)
+
+// hostPoisonCanary is sent as X-Forwarded-Host. Like corsProbeOrigin it is
+// on a reserved domain, so a reflection can't be a coincidence.
+const hostPoisonCanary = "poison-canary.example"
+
+// hostPoisonReflection returns where resp and body reflect the canary:
+// "location", "header:<name>" or "body", or "" if they don't.
+func hostPoisonReflection(resp *http.Response, body []byte) string {
+	if strings.Contains(resp.Header.Get("Location"), hostPoisonCanary) {
+		return "location"
+	}
+	for name, vs := range resp.Header {
+		for _, v := range vs {
+			if strings.Contains(v, hostPoisonCanary) {
+				return "header:" + name
+			}
+		}
+	}
+	if bytes.Contains(body, []byte(hostPoisonCanary)) {
+		return "body"
+	}
+	return ""
+}
+
+// detectHostHeaderPoison resends r with an X-Forwarded-Host canary and
+// reports responses that reflect it. A cache keying on Host alone would
+// serve such a response to every other visitor.
+func detectHostHeaderPoison(client *http.Client, r request, headers headerArgs) {
+	resp, body, err := probe(client, r.method, r.url, r.body, withHeader(headers, "X-Forwarded-Host", hostPoisonCanary))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "request failed: %s\n", err)
+		return
+	}
+
+	if where := hostPoisonReflection(resp, body); where != "" {
+		fmt.Printf("HOST-HEADER-POISON %s (%s)\n", r.url, where)
+	}
+}
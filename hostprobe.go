@@ -0,0 +1,127 @@
+package main
+
+import (
+	"bytes"
+	"net/http"
+	"net/url"
+	"regexp"
+
+	"github.com/ahmetburakakay/urlfetcher/fetcher"
+)
+
+// hostProbe is a set of well-known paths requested once for every unique
+// host seen in the input. Responses that satisfy match are reported with
+// label and always saved; everything else is discarded silently. If detail
+// is set, its result is appended to the label as "label:detail".
+type hostProbe struct {
+	label  string
+	paths  []string
+	match  func(resp *http.Response, body []byte) bool
+	detail func(resp *http.Response, body []byte) string
+}
+
+// describe returns the label to report a matching response with.
+func (p *hostProbe) describe(resp *http.Response, body []byte) string {
+	if p.detail == nil {
+		return p.label
+	}
+	return p.label + ":" + p.detail(resp, body)
+}
+
+// requests returns one GET request per probe path against base's scheme
+// and host.
+func (p *hostProbe) requests(base *url.URL) []request {
+	reqs := make([]request, 0, len(p.paths))
+	for _, path := range p.paths {
+		u := url.URL{Scheme: base.Scheme, Host: base.Host, Path: path}
+		reqs = append(reqs, request{url: u.String(), method: "GET", probe: p})
+	}
+	return reqs
+}
+
+var sensitivePathsProbe = &hostProbe{
+	label: "SENSITIVE-PATH",
+	paths: []string{
+		"/.aws/credentials",
+		"/.aws/config",
+		"/.env",
+		"/.env.bak",
+		"/.env.local",
+		"/.env.production",
+		"/.git/config",
+		"/.git/HEAD",
+		"/.svn/entries",
+		"/.hg/hgrc",
+		"/.htpasswd",
+		"/.htaccess",
+		"/.DS_Store",
+		"/.npmrc",
+		"/.dockercfg",
+		"/.docker/config.json",
+		"/.ssh/id_rsa",
+		"/.ssh/authorized_keys",
+		"/.bash_history",
+		"/.mysql_history",
+		"/.vscode/sftp.json",
+		"/sftp-config.json",
+		"/wp-config.php.bak",
+		"/wp-config.php~",
+		"/wp-config.php.save",
+		"/wp-config.old",
+		"/config.php.bak",
+		"/configuration.php.bak",
+		"/config.json",
+		"/config.yml",
+		"/config.yaml",
+		"/config/database.yml",
+		"/database.yml",
+		"/settings.py",
+		"/local_settings.py",
+		"/appsettings.json",
+		"/web.config",
+		"/WEB-INF/web.xml",
+		"/credentials.json",
+		"/secrets.json",
+		"/composer.json",
+		"/composer.lock",
+		"/package.json",
+		"/Dockerfile",
+		"/docker-compose.yml",
+		"/id_rsa",
+		"/backup.sql",
+		"/dump.sql",
+		"/database.sql",
+		"/db.sql",
+		"/backup.zip",
+		"/backup.tar.gz",
+	},
+	match: func(resp *http.Response, body []byte) bool {
+		// None of the paths above should be served as an HTML page, so an
+		// HTML 200 is almost always a catch-all or soft 404.
+		return resp.StatusCode == http.StatusOK &&
+			len(bytes.TrimSpace(body)) != 0 &&
+			!fetcher.IsHTML(body)
+	},
+}
+
+// metricsFormatRe matches the output of common monitoring endpoints:
+// Prometheus exposition format, Spring Boot Actuator's link index and Go's
+// expvar memory stats.
+var metricsFormatRe = regexp.MustCompile(`(?m)^# (HELP|TYPE) [a-zA-Z_:][a-zA-Z0-9_:]*|"_links"\s*:\s*\{|"memstats"\s*:`)
+
+var metricsProbe = &hostProbe{
+	label: "METRICS-EXPOSED",
+	paths: []string{
+		"/metrics",
+		"/prometheus",
+		"/health",
+		"/healthz",
+		"/status",
+		"/info",
+		"/actuator",
+		"/_status",
+	},
+	match: func(resp *http.Response, body []byte) bool {
+		return resp.StatusCode == http.StatusOK && metricsFormatRe.Match(body)
+	},
+}
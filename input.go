@@ -0,0 +1,92 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"strings"
+)
+
+// readLines sends each line read from r on the returned channel, closing
+// the channel once r is exhausted. Lines longer than maxLineSize bytes stop
+// the read with an error.
+func readLines(r io.Reader, maxLineSize int) <-chan string {
+	lines := make(chan string)
+
+	go func() {
+		defer close(lines)
+
+		sc := bufio.NewScanner(r)
+		// The scanner never shrinks its initial buffer, so it mustn't
+		// start out larger than maxLineSize.
+		sc.Buffer(make([]byte, 0, min(64*1024, maxLineSize)), maxLineSize)
+		for sc.Scan() {
+			lines <- sc.Text()
+		}
+
+		if err := sc.Err(); err != nil {
+			fmt.Fprintf(os.Stderr, "failed to read input: %s\n", err)
+		}
+	}()
+
+	return lines
+}
+
+// readWordlist reads the non-empty lines of filename, with surrounding
+// whitespace trimmed.
+func readWordlist(filename string) ([]string, error) {
+	f, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var words []string
+	sc := bufio.NewScanner(f)
+	for sc.Scan() {
+		if w := strings.TrimSpace(sc.Text()); w != "" {
+			words = append(words, w)
+		}
+	}
+	return words, sc.Err()
+}
+
+// urlFilter selects input URLs with --pattern and --exclude-pattern.
+type urlFilter struct {
+	include *regexp.Regexp
+	exclude *regexp.Regexp
+}
+
+// newURLFilter compiles the --pattern and --exclude-pattern regexps. An
+// empty pattern doesn't filter anything.
+func newURLFilter(pattern, excludePattern string) (urlFilter, error) {
+	var f urlFilter
+	var err error
+	if pattern != "" {
+		f.include, err = regexp.Compile(pattern)
+		if err != nil {
+			return f, fmt.Errorf("invalid pattern: %s", err)
+		}
+	}
+	if excludePattern != "" {
+		f.exclude, err = regexp.Compile(excludePattern)
+		if err != nil {
+			return f, fmt.Errorf("invalid exclude pattern: %s", err)
+		}
+	}
+	return f, nil
+}
+
+// Match reports whether rawURL matches the pattern, if there is one, and
+// doesn't match the exclude pattern.
+func (f urlFilter) Match(rawURL string) bool {
+	return (f.include == nil || f.include.MatchString(rawURL)) &&
+		(f.exclude == nil || !f.exclude.MatchString(rawURL))
+}
+
+// Active reports whether the filter can skip any URLs.
+func (f urlFilter) Active() bool {
+	return f.include != nil || f.exclude != nil
+}
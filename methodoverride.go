@@ -0,0 +1,58 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+)
+
+// methodOverride asks for a POST to be handled as method, through header
+// or, if header is "", a _method form field.
+type methodOverride struct {
+	method string
+	header string
+}
+
+var methodOverrides = []methodOverride{
+	{http.MethodPut, "X-HTTP-Method-Override"},
+	{http.MethodDelete, "X-HTTP-Method-Override"},
+	{http.MethodDelete, "X-Method-Override"},
+	{http.MethodDelete, ""},
+}
+
+// detectMethodOverride retries a URL that answered 405 with a POST using
+// each of methodOverrides, and reports the ones that get past the 405.
+// Frameworks honour these to let HTML forms send PUT and DELETE, so they
+// can reach handlers a proxy or WAF only meant to allow some methods to.
+func detectMethodOverride(client *http.Client, r request, headers headerArgs) {
+	// An override only means something if a plain POST is refused too.
+	controlStatus := http.StatusMethodNotAllowed
+	if r.method != http.MethodPost {
+		resp, _, err := probe(client, http.MethodPost, r.url, "", headers)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "request failed: %s\n", err)
+			return
+		}
+		controlStatus = resp.StatusCode
+	}
+
+	for _, o := range methodOverrides {
+		technique, body, h := o.header, "", headerArgs(nil)
+		if o.header != "" {
+			h = withHeader(headers, o.header, o.method)
+		} else {
+			technique, body = "_method", "_method="+o.method
+			h = withHeader(headers, "Content-Type", "application/x-www-form-urlencoded")
+		}
+
+		resp, _, err := probe(client, http.MethodPost, r.url, body, h)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "request failed: %s\n", err)
+			continue
+		}
+
+		if resp.StatusCode != http.StatusMethodNotAllowed && resp.StatusCode != controlStatus {
+			fmt.Printf("METHOD-OVERRIDE-BYPASS:%s %s (%s, 405 -> %d)\n", o.method, r.url, technique, resp.StatusCode)
+		}
+	}
+}
@@ -0,0 +1,100 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path"
+	"strings"
+	"sync"
+)
+
+// probedMethods are sent directly to each URL by --detect-http-methods in
+// addition to the OPTIONS request.
+var probedMethods = []string{
+	http.MethodHead,
+	http.MethodTrace,
+	http.MethodConnect,
+	http.MethodPatch,
+}
+
+type urlMethods struct {
+	// Allow holds the methods advertised in the OPTIONS Allow header.
+	Allow []string `json:"allow"`
+	// Accepted holds the probed methods that didn't get a 405 response.
+	Accepted []string `json:"accepted"`
+}
+
+// methodMap collects the methods found for each URL.
+type methodMap struct {
+	sync.Mutex
+	urls map[string]urlMethods
+}
+
+func newMethodMap() *methodMap {
+	return &methodMap{urls: make(map[string]urlMethods)}
+}
+
+// Detect sends an OPTIONS request and each of probedMethods to rawURL and
+// records the results.
+func (m *methodMap) Detect(client *http.Client, rawURL string, headers headerArgs) {
+	found := urlMethods{Allow: []string{}, Accepted: []string{}}
+
+	resp, _, err := probe(client, http.MethodOptions, rawURL, "", headers)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "request failed: %s\n", err)
+	} else {
+		for _, v := range resp.Header.Values("Allow") {
+			for _, method := range strings.Split(v, ",") {
+				if method = strings.ToUpper(strings.TrimSpace(method)); method != "" {
+					found.Allow = append(found.Allow, method)
+				}
+			}
+		}
+	}
+
+	for _, method := range probedMethods {
+		resp, _, err := probe(client, method, rawURL, "", headers)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "request failed: %s\n", err)
+			continue
+		}
+		if resp.StatusCode != http.StatusMethodNotAllowed {
+			found.Accepted = append(found.Accepted, method)
+		}
+	}
+
+	fmt.Printf("HTTP-METHODS %s allow=%s accepted=%s\n", rawURL, methodList(found.Allow), methodList(found.Accepted))
+
+	m.Lock()
+	m.urls[rawURL] = found
+	m.Unlock()
+}
+
+// WriteFile writes the collected map as methods-map.json in the output
+// directory.
+func (m *methodMap) WriteFile(prefix string) error {
+	m.Lock()
+	defer m.Unlock()
+
+	out, err := json.MarshalIndent(m.urls, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	err = os.MkdirAll(prefix, 0750)
+	if err != nil {
+		return err
+	}
+
+	return ioutil.WriteFile(path.Join(prefix, "methods-map.json"), out, 0644)
+}
+
+func methodList(methods []string) string {
+	if len(methods) == 0 {
+		return "-"
+	}
+	return strings.Join(methods, ",")
+}
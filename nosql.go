@@ -0,0 +1,100 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"regexp"
+)
+
+var nosqlErrorRe = regexp.MustCompile(`(?i)(MongoError|MongoServerError|MongoDB|BSON|CastError|unknown (top level )?operator|\$where|couchdb)`)
+
+// nosqlOperators are injected as operator objects in place of values.
+var nosqlOperators = []struct {
+	op    string
+	value interface{}
+}{
+	{"$ne", "urlfetcher"},
+	{"$gt", ""},
+}
+
+// detectNoSQLInjection injects MongoDB-style operators into r, both as
+// array-style query parameters (?param[$ne]=x) and as operator objects in
+// place of top-level JSON body values. It reports variants that turn a 401
+// or 403 into a 200, or that produce NoSQL error messages the baseline
+// didn't contain.
+func detectNoSQLInjection(client *http.Client, r request, headers headerArgs, baseStatus int, baseBody []byte) {
+	type variant struct {
+		where string
+		url   string
+		body  string
+	}
+	var variants []variant
+
+	if u, err := url.Parse(r.url); err == nil {
+		q := u.Query()
+		for name := range q {
+			for _, o := range nosqlOperators {
+				vq := url.Values{}
+				for k, vs := range q {
+					if k != name {
+						vq[k] = vs
+					}
+				}
+				vq.Set(fmt.Sprintf("%s[%s]", name, o.op), fmt.Sprint(o.value))
+
+				vu := *u
+				vu.RawQuery = vq.Encode()
+				variants = append(variants, variant{where: name, url: vu.String(), body: r.body})
+			}
+		}
+	}
+
+	var doc map[string]interface{}
+	if json.Unmarshal([]byte(r.body), &doc) == nil {
+		for key := range doc {
+			for _, o := range nosqlOperators {
+				vdoc := make(map[string]interface{}, len(doc))
+				for k, v := range doc {
+					vdoc[k] = v
+				}
+				vdoc[key] = map[string]interface{}{o.op: o.value}
+
+				b, err := json.Marshal(vdoc)
+				if err != nil {
+					continue
+				}
+				variants = append(variants, variant{where: key, url: r.url, body: string(b)})
+			}
+		}
+	}
+
+	found := make(map[string]bool)
+	for _, v := range variants {
+		if found[v.where] {
+			continue
+		}
+
+		resp, body, err := probe(client, r.method, v.url, v.body, headers)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "request failed: %s\n", err)
+			continue
+		}
+
+		bypass := (baseStatus == http.StatusUnauthorized || baseStatus == http.StatusForbidden) &&
+			resp.StatusCode == http.StatusOK
+		errored := nosqlErrorRe.Match(body) && !nosqlErrorRe.Match(baseBody)
+
+		switch {
+		case bypass:
+			fmt.Printf("NOSQL-INJECTION-POSSIBLE:%s %s (%d -> %d)\n", v.where, v.url, baseStatus, resp.StatusCode)
+		case errored:
+			fmt.Printf("NOSQL-INJECTION-POSSIBLE:%s %s (%s)\n", v.where, v.url, nosqlErrorRe.Find(body))
+		default:
+			continue
+		}
+		found[v.where] = true
+	}
+}
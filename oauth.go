@@ -0,0 +1,51 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+)
+
+// oauthCallbackSuffixes are the path endings of OAuth 2.0 redirection
+// endpoints. /callback also covers /oauth/callback and /auth/callback.
+var oauthCallbackSuffixes = []string{
+	"/callback",
+	"/callback/",
+}
+
+// isOAuthCallback reports whether u looks like an OAuth callback URL.
+func isOAuthCallback(u *url.URL) bool {
+	p := strings.ToLower(u.Path)
+	for _, s := range oauthCallbackSuffixes {
+		if strings.HasSuffix(p, s) {
+			return true
+		}
+	}
+	return false
+}
+
+// detectOAuthMisconfig sets the redirect_uri parameter of OAuth callback
+// URLs to openRedirectTarget and reports them if the response redirects
+// there, meaning redirect_uri isn't checked against the registered one.
+func detectOAuthMisconfig(client *http.Client, r request, headers headerArgs) {
+	u, err := url.Parse(r.url)
+	if err != nil || !isOAuthCallback(u) {
+		return
+	}
+
+	q := u.Query()
+	q.Set("redirect_uri", openRedirectTarget)
+	u.RawQuery = q.Encode()
+
+	resp, body, err := probe(client, r.method, u.String(), r.body, headers)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "request failed: %s\n", err)
+		return
+	}
+
+	if evidence := openRedirectEvidence(resp, body); evidence != "" {
+		fmt.Printf("OAUTH-REDIRECT-BYPASS %s (%s)\n", r.url, evidence)
+	}
+}
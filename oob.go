@@ -0,0 +1,83 @@
+package main
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"sync"
+)
+
+// oobTracker hands out unique callback URLs on an out-of-band interaction
+// server and, once the scan is finished, checks the server's interaction log
+// for each of them. The log is fetched with a plain GET of the server URL
+// and is expected to mention the path (or hostname) of every callback it
+// received.
+type oobTracker struct {
+	base *url.URL
+
+	sync.Mutex
+	payloads map[string]oobPayload
+}
+
+type oobPayload struct {
+	label  string
+	target string
+	detail string
+}
+
+func newOOBTracker(server string) (*oobTracker, error) {
+	u, err := url.Parse(server)
+	if err != nil {
+		return nil, err
+	}
+	if u.Scheme == "" || u.Host == "" {
+		return nil, fmt.Errorf("OOB server must be an absolute URL: %s", server)
+	}
+
+	return &oobTracker{base: u, payloads: make(map[string]oobPayload)}, nil
+}
+
+// URL registers a new payload and returns the callback URL to inject. If a
+// callback for it is found by Poll, label is printed along with target and
+// detail.
+func (o *oobTracker) URL(label, target, detail string) string {
+	b := make([]byte, 8)
+	rand.Read(b)
+	token := hex.EncodeToString(b)
+
+	o.Lock()
+	o.payloads[token] = oobPayload{label: label, target: target, detail: detail}
+	o.Unlock()
+
+	u := *o.base
+	u.Path = path.Join("/", u.Path, token)
+	return u.String()
+}
+
+// Poll fetches the interaction log and reports every payload whose token
+// appears in it.
+func (o *oobTracker) Poll(client *http.Client) {
+	o.Lock()
+	defer o.Unlock()
+
+	if len(o.payloads) == 0 {
+		return
+	}
+
+	_, log, err := probe(client, http.MethodGet, o.base.String(), "", nil)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "failed to poll OOB server: %s\n", err)
+		return
+	}
+
+	for token, p := range o.payloads {
+		if bytes.Contains(log, []byte(token)) {
+			fmt.Printf("%s %s %s\n", p.label, p.target, p.detail)
+		}
+	}
+}
@@ -0,0 +1,82 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+)
+
+// pathVariants returns spellings of path that many servers normalise to the
+// same resource but that a WAF matching on the raw path may not: doubled
+// slashes, dot segments, semicolon path parameters and null bytes.
+func pathVariants(path string) []string {
+	if path == "" {
+		path = "/"
+	}
+	trimmed := strings.TrimPrefix(path, "/")
+
+	variants := []string{
+		"//" + trimmed,
+		"/./" + trimmed,
+		"/%2e/" + trimmed,
+		"/;/" + trimmed,
+		path + ";",
+		path + ";.css",
+		path + "%00",
+		path + "/",
+		path + "/.",
+	}
+
+	if i := strings.LastIndex(path, "/"); i > 0 {
+		dir, file := path[:i], path[i+1:]
+		variants = append(variants,
+			dir+"//"+file,
+			dir+"/./"+file,
+			dir+";/"+file,
+			dir+"%00/"+file,
+		)
+	}
+
+	return variants
+}
+
+// isBlockedStatus reports whether status is one WAFs and access rules
+// commonly reject requests with.
+func isBlockedStatus(status int) bool {
+	return status == http.StatusUnauthorized || status == http.StatusForbidden || status == http.StatusNotAcceptable
+}
+
+// detectPathNormalization requests each path variant of a blocked URL and
+// reports variants that get through with a 2xx response that differs from
+// the blocked one. Redirects to the clean path are not bypasses.
+func detectPathNormalization(client *http.Client, r request, headers headerArgs, baseStatus int, baseBody []byte) {
+	if !isBlockedStatus(baseStatus) {
+		return
+	}
+
+	u, err := url.Parse(r.url)
+	if err != nil {
+		return
+	}
+
+	query := ""
+	if u.RawQuery != "" {
+		query = "?" + u.RawQuery
+	}
+
+	for _, p := range pathVariants(u.EscapedPath()) {
+		variant := u.Scheme + "://" + u.Host + p + query
+
+		resp, body, err := probe(client, r.method, variant, r.body, headers)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "request failed: %s\n", err)
+			continue
+		}
+
+		if resp.StatusCode >= 200 && resp.StatusCode < 300 && responsesDiffer(baseStatus, baseBody, resp.StatusCode, body) {
+			fmt.Printf("PATH-NORM-BYPASS %s (%d -> %d)\n", variant, baseStatus, resp.StatusCode)
+		}
+	}
+}
@@ -0,0 +1,59 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"sync"
+)
+
+// pipeMu makes --pipe-to commands run one at a time, however many
+// requests are in flight.
+var pipeMu sync.Mutex
+
+// pipeBody runs command with sh, with the saved body at bodyPath as its
+// stdin, and returns what it wrote to stdout. Its stderr is passed
+// through. Commands that stop reading early, like head, are fine: the
+// broken pipe that leaves behind isn't reported. Only one command runs at
+// a time; other callers wait for it to exit.
+func pipeBody(command, bodyPath string) ([]byte, error) {
+	pipeMu.Lock()
+	defer pipeMu.Unlock()
+
+	f, err := os.Open(bodyPath)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var out bytes.Buffer
+	cmd := exec.Command("sh", "-c", command)
+	cmd.Stdout = &out
+	cmd.Stderr = os.Stderr
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return nil, err
+	}
+
+	err = cmd.Start()
+	if err != nil {
+		return nil, err
+	}
+	io.Copy(stdin, f)
+	stdin.Close()
+
+	err = cmd.Wait()
+	return out.Bytes(), err
+}
+
+// pipeSection formats the output of --pipe-to for rawURL as a section
+// headed by the URL, ending in a newline.
+func pipeSection(rawURL string, out []byte) string {
+	s := fmt.Sprintf("==> %s <==\n%s", rawURL, out)
+	if len(out) > 0 && out[len(out)-1] != '\n' {
+		s += "\n"
+	}
+	return s
+}
@@ -0,0 +1,34 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+)
+
+// detectParameterPollution sends one variant of r per query parameter with
+// that parameter repeated with a different value (?a=1&a=2), and reports
+// variants whose response differs from the baseline.
+func detectParameterPollution(client *http.Client, r request, headers headerArgs, baseStatus int, baseBody []byte) {
+	u, err := url.Parse(r.url)
+	if err != nil {
+		return
+	}
+
+	variants := rewriteParams(u, func(name, v string) string {
+		return v + "&" + name + "=urlfetcher"
+	})
+
+	for _, v := range variants {
+		resp, body, err := probe(client, r.method, v.url, r.body, headers)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "request failed: %s\n", err)
+			continue
+		}
+
+		if responsesDiffer(baseStatus, baseBody, resp.StatusCode, body) {
+			fmt.Printf("PARAM-POLLUTION:%s %s %d -> %d (%d -> %d bytes)\n", v.param, v.url, baseStatus, resp.StatusCode, len(baseBody), len(body))
+		}
+	}
+}
@@ -0,0 +1,112 @@
+package main
+
+import (
+	"context"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/ahmetburakakay/urlfetcher/fetcher"
+	"golang.org/x/time/rate"
+)
+
+// probeLimiter paces the extra requests detectors make, so that they share
+// the --delay and --rate-limit budget with the main requests. It lets
+// everything through until main replaces it.
+var probeLimiter = rate.NewLimiter(rate.Inf, 0)
+
+// probe issues a single request once probeLimiter allows it and returns
+// the response along with its fully read body. The response body is
+// closed before probe returns.
+func probe(client *http.Client, method, rawURL, body string, headers headerArgs) (*http.Response, []byte, error) {
+	err := probeLimiter.Wait(context.Background())
+	if err != nil {
+		return nil, nil, err
+	}
+	return sendProbe(client, method, rawURL, body, headers)
+}
+
+// sendProbe is probe without the wait on probeLimiter, for callers that
+// time the request and so must wait before starting the clock.
+func sendProbe(client *http.Client, method, rawURL, body string, headers headerArgs) (*http.Response, []byte, error) {
+	var b io.Reader
+	if body != "" {
+		b = strings.NewReader(body)
+	}
+
+	req, err := fetcher.NewRequest(method, rawURL, b, headers)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer resp.Body.Close()
+
+	respBody, err := ioutil.ReadAll(resp.Body)
+	return resp, respBody, err
+}
+
+// withHeader returns a copy of headers with an extra "name: value" entry.
+// Later entries win, so this overrides any user supplied value for name.
+func withHeader(headers headerArgs, name, value string) headerArgs {
+	out := make(headerArgs, len(headers), len(headers)+1)
+	copy(out, headers)
+	return append(out, name+": "+value)
+}
+
+// paramVariant is a copy of a URL with a single query parameter rewritten.
+type paramVariant struct {
+	param string
+	url   string
+}
+
+// rewriteParams returns one variant of u per query parameter, with that
+// parameter's value replaced by fn(name, value). Names and values are passed
+// to and taken from fn in their raw, still-encoded form so that payloads can
+// control their own encoding.
+func rewriteParams(u *url.URL, fn func(name, value string) string) []paramVariant {
+	pairs := strings.Split(u.RawQuery, "&")
+
+	var variants []paramVariant
+	for i, pair := range pairs {
+		if pair == "" {
+			continue
+		}
+		name, value, _ := strings.Cut(pair, "=")
+
+		rewritten := make([]string, len(pairs))
+		copy(rewritten, pairs)
+		rewritten[i] = name + "=" + fn(name, value)
+
+		v := *u
+		v.RawQuery = strings.Join(rewritten, "&")
+
+		param, err := url.QueryUnescape(name)
+		if err != nil {
+			param = name
+		}
+		variants = append(variants, paramVariant{param: param, url: v.String()})
+	}
+
+	return variants
+}
+
+// responsesDiffer reports whether a probe response differs enough from a
+// baseline to suggest different server-side handling: either the status
+// code changed or the body length moved by more than 10%.
+func responsesDiffer(baseStatus int, baseBody []byte, status int, body []byte) bool {
+	if baseStatus != status {
+		return true
+	}
+
+	diff := len(body) - len(baseBody)
+	if diff < 0 {
+		diff = -diff
+	}
+	return diff*10 > len(baseBody)
+}
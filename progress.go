@@ -0,0 +1,120 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"sync/atomic"
+	"time"
+)
+
+// progressReport is printed as one JSON line per --progress-interval. Total
+// and ETA are omitted when the number of requests isn't known.
+type progressReport struct {
+	Done       int64   `json:"done"`
+	Total      int64   `json:"total,omitempty"`
+	Rate       float64 `json:"rate"`
+	ETASeconds *int64  `json:"eta_seconds,omitempty"`
+}
+
+// progress counts completed requests and periodically reports them, with
+// the rate and the estimated time left, for --progress-interval.
+type progress struct {
+	done  int64
+	total int64
+	start time.Time
+	stop  chan struct{}
+}
+
+// newProgress returns a progress for total requests, or an unknown number
+// if total is 0.
+func newProgress(total int64) *progress {
+	return &progress{total: total, start: time.Now(), stop: make(chan struct{})}
+}
+
+// Add records that another request has completed.
+func (p *progress) Add() {
+	atomic.AddInt64(&p.done, 1)
+}
+
+// Report writes the current progress to w.
+func (p *progress) Report(w io.Writer) {
+	done := atomic.LoadInt64(&p.done)
+
+	r := progressReport{Done: done}
+	if elapsed := time.Since(p.start).Seconds(); elapsed > 0 {
+		r.Rate = math.Round(float64(done)/elapsed*10) / 10
+	}
+	if p.total > 0 {
+		r.Total = p.total
+		if r.Rate > 0 && done <= p.total {
+			eta := int64(float64(p.total-done) / r.Rate)
+			r.ETASeconds = &eta
+		}
+	}
+
+	b, err := json.Marshal(r)
+	if err != nil {
+		return
+	}
+	fmt.Fprintln(w, string(b))
+}
+
+// Run reports progress to stderr every interval until Stop is called.
+func (p *progress) Run(interval time.Duration) {
+	t := time.NewTicker(interval)
+	defer t.Stop()
+
+	for {
+		select {
+		case <-t.C:
+			p.Report(os.Stderr)
+		case <-p.stop:
+			return
+		}
+	}
+}
+
+// Stop ends Run.
+func (p *progress) Stop() {
+	close(p.stop)
+}
+
+// countLines returns the number of lines in the named file, counting a
+// final line without a newline.
+func countLines(filename string) (int64, error) {
+	f, err := os.Open(filename)
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+
+	var n int64
+	var last byte = '\n'
+	r := bufio.NewReader(f)
+	buf := make([]byte, 64*1024)
+	for {
+		c, err := r.Read(buf)
+		for _, b := range buf[:c] {
+			if b == '\n' {
+				n++
+			}
+		}
+		if c > 0 {
+			last = buf[c-1]
+		}
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return 0, err
+		}
+	}
+	if last != '\n' {
+		n++
+	}
+	return n, nil
+}
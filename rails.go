@@ -0,0 +1,14 @@
+package main
+
+import "regexp"
+
+// railsSecretRe matches a secret_key_base assignment as it appears in
+// secrets.yml, credentials dumps, .env files and Ruby hashes printed by
+// debug pages. Rails generates 128 hex digit secrets; 64 is accepted for
+// hand-made ones.
+var railsSecretRe = regexp.MustCompile(`(?i)secret_key_base["']?\s*(?::|=>?)\s*["']?[0-9a-f]{64,}`)
+
+// leaksRailsSecret reports whether body contains a Rails secret_key_base.
+func leaksRailsSecret(body []byte) bool {
+	return railsSecretRe.Match(body)
+}
@@ -0,0 +1,101 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"math/rand"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/ahmetburakakay/urlfetcher/fetcher"
+)
+
+// detectRateLimit sends a rapid burst of requests to rawURL, bypassing the
+// global limiter, and reports the first 429 or 503 response it sees.
+func detectRateLimit(client *http.Client, method, rawURL, body string, headers headerArgs, burst int, prefix string) {
+	for i := 0; i < burst; i++ {
+		var b io.Reader
+		if body != "" {
+			b = strings.NewReader(body)
+		}
+
+		req, err := fetcher.NewRequest(method, rawURL, b, headers)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "failed to create request: %s\n", err)
+			return
+		}
+
+		resp, err := client.Do(req)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "request failed: %s\n", err)
+			return
+		}
+		io.Copy(ioutil.Discard, resp.Body)
+		resp.Body.Close()
+
+		if resp.StatusCode != http.StatusTooManyRequests && resp.StatusCode != http.StatusServiceUnavailable {
+			continue
+		}
+
+		retryAfter := resp.Header.Get("Retry-After")
+		if retryAfter == "" {
+			retryAfter = "-"
+		}
+
+		fmt.Printf("RATE-LIMITED %s %d after %d requests (Retry-After: %s)\n", rawURL, resp.StatusCode, i+1, retryAfter)
+
+		err = appendLine(prefix, "rate-limited.txt", fmt.Sprintf("%s %d %s", rawURL, resp.StatusCode, retryAfter))
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "failed to write file contents: %s\n", err)
+		}
+		return
+	}
+}
+
+// rateLimitBypassHeaders are tried one at a time against a rate limited
+// URL. The client IP headers get a new random address each time, so a
+// limiter keying on them sees a fresh client.
+var rateLimitBypassHeaders = []struct {
+	name     string
+	value    string
+	randomIP bool
+}{
+	{"X-Forwarded-For", "", true},
+	{"X-Real-IP", "", true},
+	{"X-Originating-IP", "", true},
+	{"X-Client-IP", "", true},
+	{"X-Remote-IP", "", true},
+	{"X-Remote-Addr", "", true},
+	{"X-Originating-IP", "127.0.0.1", false},
+	{"X-RateLimit-Bypass", "1", false},
+}
+
+// randomIPv4 returns a random address outside 0/8 and the multicast and
+// reserved ranges.
+func randomIPv4() string {
+	return fmt.Sprintf("%d.%d.%d.%d", 1+rand.Intn(223), rand.Intn(256), rand.Intn(256), 1+rand.Intn(254))
+}
+
+// detectRateLimitBypass retries a URL that answered 429 with each of
+// rateLimitBypassHeaders and reports the headers that get a different
+// response, meaning the limiter trusts a client-supplied value.
+func detectRateLimitBypass(client *http.Client, r request, headers headerArgs) {
+	for _, h := range rateLimitBypassHeaders {
+		value := h.value
+		if h.randomIP {
+			value = randomIPv4()
+		}
+
+		resp, _, err := probe(client, r.method, r.url, r.body, withHeader(headers, h.name, value))
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "request failed: %s\n", err)
+			continue
+		}
+
+		if resp.StatusCode != http.StatusTooManyRequests {
+			fmt.Printf("RATE-LIMIT-BYPASS:%s %s (%s: %s, 429 -> %d)\n", h.name, r.url, h.name, value, resp.StatusCode)
+		}
+	}
+}
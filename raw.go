@@ -0,0 +1,86 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/tls"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/ahmetburakakay/urlfetcher/fetcher"
+)
+
+// rawRoundTrip writes payload unmodified over a new connection to u's host
+// and parses the first response read back. It bypasses net/http so that
+// requests the standard client would refuse to send, such as ones with
+// conflicting framing headers, can be made. The connection is made with
+// dial, so it goes through the same resolver and network as the client,
+// but never through a proxy. Connecting, including the TLS handshake, must
+// finish within connectTimeout, and sending the payload and reading the
+// response within timeout. A zero timeout means no limit.
+func rawRoundTrip(dial fetcher.DialFunc, u *url.URL, payload []byte, connectTimeout, timeout time.Duration) (*http.Response, []byte, error) {
+	host := u.Host
+	if u.Port() == "" {
+		if u.Scheme == "https" {
+			host = net.JoinHostPort(u.Hostname(), "443")
+		} else {
+			host = net.JoinHostPort(u.Hostname(), "80")
+		}
+	}
+
+	ctx := context.Background()
+	if connectTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, connectTimeout)
+		defer cancel()
+	}
+
+	conn, err := dial(ctx, "tcp", host)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer conn.Close()
+
+	if u.Scheme == "https" {
+		tc := tls.Client(conn, &tls.Config{ServerName: u.Hostname()})
+		if err := tc.HandshakeContext(ctx); err != nil {
+			return nil, nil, err
+		}
+		conn = tc
+	}
+
+	if timeout > 0 {
+		conn.SetDeadline(time.Now().Add(timeout))
+	}
+
+	if _, err := conn.Write(payload); err != nil {
+		return nil, nil, err
+	}
+
+	resp, err := http.ReadResponse(bufio.NewReader(conn), nil)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer resp.Body.Close()
+
+	body, err := ioutil.ReadAll(resp.Body)
+	return resp, body, err
+}
+
+// rawRequest formats an HTTP/1.1 request with headers in the given order.
+// Host is added first and Connection: close last.
+func rawRequest(method string, u *url.URL, headers []string, body string) []byte {
+	var b bytes.Buffer
+	b.WriteString(method + " " + fetcher.RequestTarget(u) + " HTTP/1.1\r\n")
+	b.WriteString("Host: " + u.Host + "\r\n")
+	for _, h := range headers {
+		b.WriteString(h + "\r\n")
+	}
+	b.WriteString("Connection: close\r\n\r\n")
+	b.WriteString(body)
+	return b.Bytes()
+}
@@ -0,0 +1,77 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+
+	"github.com/ahmetburakakay/urlfetcher/fetcher"
+)
+
+// detectDNSRebinding resends r with the Host header set to the address
+// its hostname resolves to, and reports it if the server answers the same
+// way. A server that doesn't check the Host header serves any name
+// pointed at it, which is what DNS rebinding relies on to reach it from a
+// victim's browser.
+func detectDNSRebinding(client *http.Client, r request, headers headerArgs, baseStatus int, baseBody []byte) {
+	if baseStatus < 200 || baseStatus >= 300 {
+		return
+	}
+
+	u, err := url.Parse(r.url)
+	if err != nil || net.ParseIP(u.Hostname()) != nil {
+		return
+	}
+
+	ips, err := net.LookupIP(u.Hostname())
+	if err != nil || len(ips) == 0 {
+		return
+	}
+	host := ips[0].String()
+	if ips[0].To4() == nil {
+		host = "[" + host + "]"
+	}
+	if port := u.Port(); port != "" {
+		host += ":" + port
+	}
+
+	// Go sends req.Host rather than any Host header, so this can't go
+	// through probe.
+	var b io.Reader
+	if r.body != "" {
+		b = strings.NewReader(r.body)
+	}
+	req, err := fetcher.NewRequest(r.method, r.url, b, headers)
+	if err != nil {
+		return
+	}
+	req.Host = host
+
+	err = probeLimiter.Wait(context.Background())
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "rate limiter error: %s\n", err)
+		return
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "request failed: %s\n", err)
+		return
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "request failed: %s\n", err)
+		return
+	}
+
+	if !responsesDiffer(baseStatus, baseBody, resp.StatusCode, body) {
+		fmt.Printf("DNS-REBIND-POSSIBLE %s (Host: %s accepted)\n", r.url, host)
+	}
+}
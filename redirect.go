@@ -0,0 +1,72 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"regexp"
+	"strings"
+)
+
+// openRedirectTarget replaces URL-like parameter values. It is on a
+// reserved domain so that finding it in a response can't be a coincidence.
+const openRedirectTarget = "https://evil.example"
+
+// clientRedirectRe matches redirects done by the page rather than the
+// server: meta refresh tags and assignments to window.location.
+var clientRedirectRe = regexp.MustCompile(`(?i)<meta[^>]+http-equiv=["']?refresh[^>]+evil\.example|location(?:\.href)?\s*=\s*["']https://evil\.example|location\.(?:replace|assign)\(\s*["']https://evil\.example`)
+
+// openRedirectEvidence returns how resp redirects to openRedirectTarget:
+// "location" for the Location header, "body" for a client-side redirect
+// in the body, or "" if it doesn't.
+func openRedirectEvidence(resp *http.Response, body []byte) string {
+	loc := resp.Header.Get("Location")
+	if strings.HasPrefix(loc, openRedirectTarget) || strings.HasPrefix(loc, strings.TrimPrefix(openRedirectTarget, "https:")) {
+		return "location"
+	}
+	if bytes.Contains(body, []byte(openRedirectTarget)) && clientRedirectRe.Match(body) {
+		return "body"
+	}
+	return ""
+}
+
+// detectOpenRedirect replaces each URL-like query parameter of r, one at a
+// time, with openRedirectTarget and reports parameters that make the
+// response redirect there.
+func detectOpenRedirect(client *http.Client, r request, headers headerArgs) {
+	u, err := url.Parse(r.url)
+	if err != nil {
+		return
+	}
+
+	injected := make(map[string]bool)
+	variants := rewriteParams(u, func(name, value string) string {
+		param, err := url.QueryUnescape(name)
+		if err != nil {
+			param = name
+		}
+		if !ssrfCandidate(param, value) {
+			return value
+		}
+		injected[param] = true
+		return url.QueryEscape(openRedirectTarget)
+	})
+
+	for _, v := range variants {
+		if !injected[v.param] {
+			continue
+		}
+
+		resp, body, err := probe(client, r.method, v.url, r.body, headers)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "request failed: %s\n", err)
+			continue
+		}
+
+		if evidence := openRedirectEvidence(resp, body); evidence != "" {
+			fmt.Printf("OPEN-REDIRECT:%s %s (%s)\n", v.param, r.url, evidence)
+		}
+	}
+}
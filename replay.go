@@ -0,0 +1,85 @@
+package main
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// parseHeadersFile reconstructs the request recorded at the top of a saved
+// .headers file: a "METHOD URL" line, a blank line, the request headers as
+// "> Name: value" lines, a blank line and then the body, if any, ahead of
+// the "< " response lines. "# " comment lines after the request line are
+// skipped. A body recorded as "@file", from -b @file, is streamed from
+// that file again.
+func parseHeadersFile(data string) (request, error) {
+	lines := strings.Split(data, "\n")
+
+	method, rawURL, ok := strings.Cut(lines[0], " ")
+	if !ok || method == "" || rawURL == "" {
+		return request{}, fmt.Errorf("missing request line")
+	}
+	r := request{method: method, url: rawURL, headers: headerArgs{}}
+
+	i := 1
+	for ; i < len(lines) && (lines[i] == "" || strings.HasPrefix(lines[i], "# ")); i++ {
+	}
+	for ; i < len(lines) && strings.HasPrefix(lines[i], "> "); i++ {
+		r.headers = append(r.headers, strings.TrimPrefix(lines[i], "> "))
+	}
+	if i < len(lines) && lines[i] == "" {
+		i++
+	}
+
+	end := len(lines)
+	for end > i && (strings.HasPrefix(lines[end-1], "< ") || lines[end-1] == "") {
+		end--
+	}
+	if end > i {
+		r.body = strings.Join(lines[i:end], "\n")
+	}
+	if strings.HasPrefix(r.body, "@") && !strings.Contains(r.body, "\n") {
+		r.bodyFile = r.body[1:]
+		r.body = ""
+	}
+
+	return r, nil
+}
+
+// redirectHopFile matches the <hash>_<n>.headers files --save-redirect-chain
+// writes for each redirect, which describe responses rather than requests
+// to replay.
+var redirectHopFile = regexp.MustCompile(`_[0-9]+\.headers$`)
+
+// loadReplay parses every .headers file below dir, except redirect hops.
+// extra headers are added after the recorded ones, so they override them.
+func loadReplay(dir string, extra headerArgs) ([]request, error) {
+	var reqs []request
+	err := filepath.WalkDir(dir, func(p string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() || filepath.Ext(p) != ".headers" || redirectHopFile.MatchString(p) {
+			return err
+		}
+
+		data, err := os.ReadFile(p)
+		if err != nil {
+			return err
+		}
+
+		r, err := parseHeadersFile(string(data))
+		if err != nil {
+			return fmt.Errorf("%s: %w", p, err)
+		}
+		if r.bodyFile != "" {
+			if _, err := os.Stat(r.bodyFile); err != nil {
+				return fmt.Errorf("%s: %w", p, err)
+			}
+		}
+		r.headers = append(r.headers, extra...)
+		reqs = append(reqs, r)
+		return nil
+	})
+	return reqs, err
+}
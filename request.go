@@ -0,0 +1,235 @@
+package main
+
+import (
+	"compress/gzip"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ahmetburakakay/urlfetcher/fetcher"
+)
+
+// request describes a single fetch. The method and body start out as the
+// global --method and --body values but may be overridden per input line.
+// Requests generated by a host probe rather than read from the input carry
+// that probe, and requests expanded from a --wordlist carry the word that
+// replaced FUZZ. Replayed requests carry their own headers, which are used
+// in place of the -H headers. With -b @file, bodyFile names the file to
+// stream as the body and body is empty. A non-zero timeout, read from the
+// input with --timeout-per-url, replaces the client's timeout.
+type request struct {
+	url      string
+	method   string
+	body     string
+	bodyFile string
+	probe    *hostProbe
+	word     string
+	headers  headerArgs
+	timeout  time.Duration
+}
+
+// fuzzPlaceholder is replaced by each --wordlist entry in turn.
+const fuzzPlaceholder = "FUZZ"
+
+// fuzz returns one copy of r per word with every FUZZ in its URL and body
+// replaced by that word, or just r if neither contains FUZZ. Words are
+// substituted as-is, without URL encoding.
+func (r request) fuzz(words []string) []request {
+	if !strings.Contains(r.url, fuzzPlaceholder) && !strings.Contains(r.body, fuzzPlaceholder) {
+		return []request{r}
+	}
+
+	reqs := make([]request, 0, len(words))
+	for _, w := range words {
+		f := r
+		f.url = strings.ReplaceAll(r.url, fuzzPlaceholder, w)
+		f.body = strings.ReplaceAll(r.body, fuzzPlaceholder, w)
+		f.word = w
+		reqs = append(reqs, f)
+	}
+	return reqs
+}
+
+// build creates the *http.Request for r. When a body is present and the
+// method was left at the GET default, the method is switched to POST; each
+// worker builds from its own copy of the request, so this never affects
+// the --method default other requests start from. A bodyFile is sent with
+// chunked transfer encoding as it is read, so it is never held in memory;
+// the file is closed once the request is sent.
+func (r *request) build(headers headerArgs) (*http.Request, error) {
+	if r.bodyFile != "" {
+		if r.method == "GET" {
+			r.method = "POST"
+		}
+
+		f, err := os.Open(r.bodyFile)
+		if err != nil {
+			return nil, err
+		}
+
+		req, err := fetcher.NewRequest(r.method, r.url, f, headers)
+		if err != nil {
+			f.Close()
+			return nil, err
+		}
+		req.ContentLength = -1
+		return req, nil
+	}
+
+	var b io.Reader
+	if r.body != "" {
+		b = strings.NewReader(r.body)
+		if r.method == "GET" {
+			r.method = "POST"
+		}
+	}
+
+	return fetcher.NewRequest(r.method, r.url, b, headers)
+}
+
+// encodeBody returns body encoded for --encode: "url", "base64" or "hex".
+// Any other encoding leaves body unchanged.
+func encodeBody(encoding, body string) string {
+	switch encoding {
+	case "url":
+		return url.QueryEscape(body)
+	case "base64":
+		return base64.StdEncoding.EncodeToString([]byte(body))
+	case "hex":
+		return hex.EncodeToString([]byte(body))
+	default:
+		return body
+	}
+}
+
+// gzipBody returns a reader of body compressed with gzip as it is read,
+// for --compress-body. body is closed once it has been read, or when the
+// returned reader is closed early.
+func gzipBody(body io.ReadCloser) io.ReadCloser {
+	pr, pw := io.Pipe()
+	go func() {
+		defer body.Close()
+
+		zw := gzip.NewWriter(pw)
+		_, err := io.Copy(zw, body)
+		if err == nil {
+			err = zw.Close()
+		}
+		pw.CloseWithError(err)
+	}()
+	return pr
+}
+
+// parseTSVLine parses a "URL<TAB>METHOD[<TAB>BODY]" input line. The method
+// and, when present, the body override those already set on def.
+func parseTSVLine(line string, def request) (request, error) {
+	fields := strings.Split(line, "\t")
+	if len(fields) < 2 || len(fields) > 3 {
+		return request{}, fmt.Errorf("expected 2 or 3 tab-separated columns, got %d", len(fields))
+	}
+
+	r := def
+	r.url = fields[0]
+	r.method = strings.ToUpper(strings.TrimSpace(fields[1]))
+	if len(fields) == 3 {
+		r.body = fields[2]
+		r.bodyFile = ""
+	}
+
+	return r, nil
+}
+
+// parseMethodLine parses a "URL [METHOD [BODY]]" input line for
+// --method-per-url, whose fields are separated by runs of spaces or tabs.
+// The body is the rest of the line, so it may contain spaces itself. The
+// method and body, when present, override those already set on def.
+func parseMethodLine(line string, def request) request {
+	r := def
+	rest := strings.TrimSpace(line)
+
+	var method string
+	r.url, rest = cutField(rest)
+	method, rest = cutField(rest)
+	if method != "" {
+		r.method = strings.ToUpper(method)
+	}
+	if rest != "" {
+		r.body = rest
+		r.bodyFile = ""
+	}
+
+	return r
+}
+
+// cutField splits s at the first run of spaces or tabs.
+func cutField(s string) (field, rest string) {
+	i := strings.IndexAny(s, " \t")
+	if i < 0 {
+		return s, ""
+	}
+	return s[:i], strings.TrimLeft(s[i:], " \t")
+}
+
+// parseTimeoutLine parses a "URL[<TAB>SECONDS]" input line for
+// --timeout-per-url. Lines without a tab are taken as a URL using the
+// global timeout.
+func parseTimeoutLine(line string, def request) (request, error) {
+	r := def
+	rawURL, seconds, ok := strings.Cut(line, "\t")
+	r.url = rawURL
+	if !ok {
+		return r, nil
+	}
+
+	s, err := strconv.ParseFloat(strings.TrimSpace(seconds), 64)
+	if err != nil || s <= 0 {
+		return request{}, fmt.Errorf("invalid timeout: %s", seconds)
+	}
+	r.timeout = time.Duration(s * float64(time.Second))
+
+	return r, nil
+}
+
+// normalizeURL lowercases the scheme and host of rawURL, drops the port if
+// it is the scheme's default and the fragment, which is never sent, and
+// sorts the query parameters by name with repeated name=value pairs
+// removed, so that equivalent URLs compare equal.
+func normalizeURL(rawURL string) (string, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return "", err
+	}
+
+	u.Scheme = strings.ToLower(u.Scheme)
+	u.Host = strings.ToLower(u.Host)
+	if port := u.Port(); (u.Scheme == "http" && port == "80") || (u.Scheme == "https" && port == "443") {
+		u.Host = strings.TrimSuffix(u.Host, ":"+port)
+	}
+
+	u.Fragment = ""
+	u.RawFragment = ""
+
+	if u.RawQuery != "" {
+		q := u.Query()
+		for name, values := range q {
+			var unique []string
+			for _, v := range values {
+				if !slices.Contains(unique, v) {
+					unique = append(unique, v)
+				}
+			}
+			q[name] = unique
+		}
+		u.RawQuery = q.Encode()
+	}
+
+	return u.String(), nil
+}
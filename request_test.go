@@ -0,0 +1,71 @@
+package main
+
+import (
+	"net/http"
+	"sync"
+	"testing"
+)
+
+func TestNormalizeURL(t *testing.T) {
+	tests := []struct {
+		in   string
+		want string
+	}{
+		{"http://example.com:80/a", "http://example.com/a"},
+		{"https://example.com:443/a", "https://example.com/a"},
+		{"https://example.com:8443/a", "https://example.com:8443/a"},
+		{"http://example.com:443/a", "http://example.com:443/a"},
+		{"HTTP://Example.COM/Path", "http://example.com/Path"},
+		{"http://example.com/search?b=2&a=1", "http://example.com/search?a=1&b=2"},
+		{"http://example.com/search?a=1&b=2&a=1", "http://example.com/search?a=1&b=2"},
+		{"http://example.com/search?a=2&a=1", "http://example.com/search?a=2&a=1"},
+		{"http://example.com/page#section", "http://example.com/page"},
+		{"http://example.com/page?b=1&a=2#x", "http://example.com/page?a=2&b=1"},
+		{"http://example.com/", "http://example.com/"},
+	}
+
+	for _, tt := range tests {
+		got, err := normalizeURL(tt.in)
+		if err != nil {
+			t.Errorf("normalizeURL(%q) returned error: %s", tt.in, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("normalizeURL(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestNormalizeURLError(t *testing.T) {
+	for _, in := range []string{"http://exa mple.com/", "http://[::1/", "%zz"} {
+		if got, err := normalizeURL(in); err == nil {
+			t.Errorf("normalizeURL(%q) = %q, want an error", in, got)
+		}
+	}
+}
+
+func TestRequestBuildConcurrent(t *testing.T) {
+	base := request{method: http.MethodGet, url: "http://example.com/", body: "a=1"}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 16; i++ {
+		wg.Add(1)
+		// Like the workers in main, each goroutine gets its own copy.
+		go func(r request) {
+			defer wg.Done()
+			req, err := r.build(nil)
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			if req.Method != http.MethodPost {
+				t.Errorf("method is %s, want POST", req.Method)
+			}
+		}(base)
+	}
+	wg.Wait()
+
+	if base.method != http.MethodGet {
+		t.Errorf("base request's method changed to %s", base.method)
+	}
+}
@@ -0,0 +1,49 @@
+package main
+
+import (
+	"crypto/rand"
+	"fmt"
+)
+
+// defaultRequestIDHeader is the header --request-id-header sets when it's
+// given without a name.
+const defaultRequestIDHeader = "X-Request-ID"
+
+// newUUID returns a random (version 4) UUID as described in RFC 4122.
+func newUUID() string {
+	b := make([]byte, 16)
+	rand.Read(b)
+	b[6] = b[6]&0x0f | 0x40
+	b[8] = b[8]&0x3f | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:])
+}
+
+// optionalArg is a flag that can be given on its own, like a boolean, to
+// use a default value, or as --flag=value to use another one.
+type optionalArg struct {
+	value string
+	def   string
+}
+
+func (o *optionalArg) Set(val string) error {
+	switch val {
+	case "true":
+		o.value = o.def
+	case "false":
+		o.value = ""
+	default:
+		o.value = val
+	}
+	return nil
+}
+
+func (o *optionalArg) String() string {
+	if o == nil {
+		return ""
+	}
+	return o.value
+}
+
+func (o *optionalArg) IsBoolFlag() bool {
+	return true
+}
@@ -0,0 +1,36 @@
+package main
+
+import (
+	"net/http"
+	"regexp"
+)
+
+// errorPagePatterns match the server signature in the default error pages
+// of Apache, nginx and IIS. The first group of each is the version.
+var errorPagePatterns = namedPatterns{
+	{"Apache", regexp.MustCompile(`<address>Apache/(\d+(?:\.\d+)+)`)},
+	{"nginx", regexp.MustCompile(`<center>nginx/(\d+(?:\.\d+)+)</center>`)},
+	{"IIS", regexp.MustCompile(`IIS (\d+(?:\.\d+)+) Detailed Error|Microsoft-IIS/(\d+(?:\.\d+)+)`)},
+}
+
+// errorPageServerVersion returns the "name/version" of the server whose
+// default error page resp is, or "" if it isn't an error response or
+// doesn't include a version.
+func errorPageServerVersion(resp *http.Response, body []byte) string {
+	if resp.StatusCode < 400 {
+		return ""
+	}
+
+	for _, p := range errorPagePatterns {
+		m := p.re.FindSubmatch(body)
+		if m == nil {
+			continue
+		}
+		for _, v := range m[1:] {
+			if len(v) > 0 {
+				return p.name + "/" + string(v)
+			}
+		}
+	}
+	return ""
+}
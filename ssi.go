@@ -0,0 +1,60 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"strings"
+)
+
+// ssiPayload sets a variable and echoes it back, followed by a literal
+// suffix. Only an evaluated payload produces ssiMarker in one piece;
+// reflecting the payload verbatim or escaped doesn't.
+const (
+	ssiPayload = `<!--#set var="u" value="urlfetcher" --><!--#echo var="u" -->-ssi`
+	ssiMarker  = "urlfetcher-ssi"
+)
+
+// ssiCandidate reports whether resp comes from an .shtml page or a server
+// that can process Server-Side Includes.
+func ssiCandidate(resp *http.Response) bool {
+	switch strings.ToLower(path.Ext(resp.Request.URL.Path)) {
+	case ".shtml", ".shtm", ".stm":
+		return true
+	}
+
+	server := strings.ToLower(resp.Header.Get("Server"))
+	return strings.Contains(server, "apache") || strings.Contains(server, "nginx")
+}
+
+// detectSSIInjection injects a benign SSI directive into every query
+// parameter of r and reports parameters where it is evaluated.
+func detectSSIInjection(client *http.Client, r request, headers headerArgs, baseBody []byte) {
+	if bytes.Contains(baseBody, []byte(ssiMarker)) {
+		return
+	}
+
+	u, err := url.Parse(r.url)
+	if err != nil {
+		return
+	}
+
+	variants := rewriteParams(u, func(_, _ string) string {
+		return url.QueryEscape(ssiPayload)
+	})
+
+	for _, v := range variants {
+		_, body, err := probe(client, r.method, v.url, r.body, headers)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "request failed: %s\n", err)
+			continue
+		}
+
+		if bytes.Contains(body, []byte(ssiMarker)) {
+			fmt.Printf("SSI-INJECTION:%s %s\n", v.param, v.url)
+		}
+	}
+}
@@ -0,0 +1,88 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"regexp"
+	"strings"
+)
+
+// ssrfParamNames are query parameter names that commonly hold a URL the
+// server fetches or redirects to.
+var ssrfParamNames = map[string]bool{
+	"url": true, "uri": true, "link": true, "src": true, "source": true,
+	"dest": true, "destination": true, "redirect": true, "redirect_uri": true,
+	"redirect_url": true, "return": true, "return_url": true, "returnurl": true,
+	"next": true, "target": true, "callback": true, "webhook": true,
+	"feed": true, "host": true, "domain": true, "site": true, "image": true,
+	"img": true, "proxy": true, "fetch": true, "load": true, "file": true,
+}
+
+var urlValueRe = regexp.MustCompile(`(?i)^(?:https?:)?//|^[a-z0-9.-]+\.[a-z]{2,}(?:[/:?]|$)`)
+
+// ssrfCandidate reports whether the query parameter name=value looks like
+// it holds a URL, by its name or its (decoded) value.
+func ssrfCandidate(name, value string) bool {
+	if ssrfParamNames[strings.ToLower(name)] {
+		return true
+	}
+	if v, err := url.QueryUnescape(value); err == nil {
+		value = v
+	}
+	return urlValueRe.MatchString(value)
+}
+
+// ssrfHeaders are request headers applications use to build absolute URLs,
+// for redirects and for requests of their own. X-Forwarded-Host gets the
+// callback without a scheme, as it is usually prefixed with one.
+var ssrfHeaders = []string{"Referer", "X-Forwarded-Host"}
+
+// detectSSRF replaces each URL-like query parameter of r with a unique OOB
+// callback, one parameter per request, and sends one more request with
+// callbacks in ssrfHeaders. A callback, found when the OOB server is
+// polled, is reported with the parameter or header that triggered it.
+func detectSSRF(client *http.Client, oob *oobTracker, r request, headers headerArgs) {
+	u, err := url.Parse(r.url)
+	if err != nil {
+		return
+	}
+
+	injected := make(map[string]bool)
+	variants := rewriteParams(u, func(name, value string) string {
+		param, err := url.QueryUnescape(name)
+		if err != nil {
+			param = name
+		}
+		if !ssrfCandidate(param, value) {
+			return value
+		}
+		injected[param] = true
+		return url.QueryEscape(oob.URL("SSRF-POSSIBLE", r.url, param))
+	})
+
+	for _, v := range variants {
+		if !injected[v.param] {
+			continue
+		}
+		_, _, err := probe(client, r.method, v.url, r.body, headers)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "request failed: %s\n", err)
+		}
+	}
+
+	h := headers
+	for _, name := range ssrfHeaders {
+		callback := oob.URL("SSRF-POSSIBLE", r.url, "header:"+name)
+		if name == "X-Forwarded-Host" {
+			callback = strings.TrimPrefix(strings.TrimPrefix(callback, "https://"), "http://")
+		}
+		h = withHeader(h, name, callback)
+	}
+
+	_, _, err = probe(client, r.method, r.url, r.body, h)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "request failed: %s\n", err)
+	}
+}
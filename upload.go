@@ -0,0 +1,71 @@
+package main
+
+import (
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+var (
+	formRe      = regexp.MustCompile(`(?is)<form\b([^>]*)>(.*?)</form>`)
+	fileInputRe = regexp.MustCompile(`(?is)<input\b[^>]*\btype\s*=\s*["']?file\b[^>]*>`)
+	multipartRe = regexp.MustCompile(`(?i)\benctype\s*=\s*["']?multipart/form-data`)
+	actionRe    = regexp.MustCompile(`(?i)\baction\s*=\s*(?:"([^"]*)"|'([^']*)'|([^\s>]+))`)
+	acceptRe    = regexp.MustCompile(`(?i)\baccept\s*=\s*(?:"([^"]*)"|'([^']*)'|([^\s>]+))`)
+)
+
+type uploadForm struct {
+	action string
+	accept []string
+}
+
+// findUploadForms returns every form in body that either contains a file
+// input or is submitted as multipart/form-data. Form actions are resolved
+// against base.
+func findUploadForms(base *url.URL, body []byte) []uploadForm {
+	var forms []uploadForm
+
+	for _, m := range formRe.FindAllSubmatch(body, -1) {
+		attrs, inner := m[1], m[2]
+
+		inputs := fileInputRe.FindAll(inner, -1)
+		if len(inputs) == 0 && !multipartRe.Match(attrs) {
+			continue
+		}
+
+		action := base.String()
+		if a := attrValue(actionRe, attrs); a != "" {
+			if u, err := base.Parse(a); err == nil {
+				action = u.String()
+			}
+		}
+
+		var accept []string
+		for _, in := range inputs {
+			for _, ext := range strings.Split(attrValue(acceptRe, in), ",") {
+				if ext = strings.TrimSpace(ext); ext != "" {
+					accept = append(accept, ext)
+				}
+			}
+		}
+
+		forms = append(forms, uploadForm{action: action, accept: accept})
+	}
+
+	return forms
+}
+
+// attrValue returns the first value matched by an attribute regex, whichever
+// quoting style was used.
+func attrValue(re *regexp.Regexp, tag []byte) string {
+	m := re.FindSubmatch(tag)
+	if m == nil {
+		return ""
+	}
+	for _, v := range m[1:] {
+		if len(v) != 0 {
+			return string(v)
+		}
+	}
+	return ""
+}
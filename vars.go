@@ -0,0 +1,70 @@
+package main
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// templateVar is a --var NAME=value definition. value may use the
+// built-in variables, and {{NAME}} is replaced by the result.
+type templateVar struct {
+	name  string
+	value string
+}
+
+type templateVars []templateVar
+
+// parseVars parses --var arguments of the form NAME=value.
+func parseVars(args []string) (templateVars, error) {
+	vars := make(templateVars, 0, len(args))
+	for _, a := range args {
+		name, value, ok := strings.Cut(a, "=")
+		name = strings.TrimSpace(name)
+		if !ok || name == "" || strings.ContainsAny(name, "{}") {
+			return nil, fmt.Errorf("invalid variable %q: expected NAME=value", a)
+		}
+		vars = append(vars, templateVar{name, value})
+	}
+	return vars, nil
+}
+
+// expand returns r and headers with {{NAME}} replaced in the URL, body
+// and headers for each of vs. The built-in variables in their values are
+// generated afresh for each call, so they differ between requests but are
+// the same everywhere within one.
+func (vs templateVars) expand(r request, headers headerArgs) (request, headerArgs) {
+	var hostname string
+	if u, err := url.Parse(r.url); err == nil {
+		hostname = u.Hostname()
+	}
+
+	b := make([]byte, 8)
+	rand.Read(b)
+
+	builtins := strings.NewReplacer(
+		"{{unix_timestamp}}", strconv.FormatInt(time.Now().Unix(), 10),
+		"{{random_hex_16}}", hex.EncodeToString(b),
+		"{{uuid}}", newUUID(),
+		"{{hostname}}", hostname,
+	)
+	pairs := make([]string, 0, 2*len(vs))
+	for _, v := range vs {
+		pairs = append(pairs, "{{"+v.name+"}}", builtins.Replace(v.value))
+	}
+	replacer := strings.NewReplacer(pairs...)
+
+	r.url = replacer.Replace(r.url)
+	r.body = replacer.Replace(r.body)
+
+	out := make(headerArgs, len(headers))
+	for i, h := range headers {
+		out[i] = replacer.Replace(h)
+	}
+
+	return r, out
+}
@@ -0,0 +1,38 @@
+package main
+
+import (
+	"crypto/tls"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"time"
+)
+
+// verboseLog receives per-request logging when -v is set, and debugLog the
+// request and response headers when -vv is set. Both discard everything by
+// default.
+var (
+	verboseLog = log.New(ioutil.Discard, "", log.LstdFlags)
+	debugLog   = log.New(ioutil.Discard, "", log.LstdFlags)
+)
+
+func logRequest(req *http.Request) {
+	verboseLog.Printf("> %s %s", req.Method, req.URL)
+	for k, vs := range req.Header {
+		for _, v := range vs {
+			debugLog.Printf("> %s %s: %s", req.URL, k, v)
+		}
+	}
+}
+
+func logResponse(req *http.Request, resp *http.Response, bodySize int, elapsed time.Duration) {
+	verboseLog.Printf("< %s %s %s content-length=%d time=%s", req.Method, req.URL, resp.Status, bodySize, elapsed.Round(time.Millisecond))
+	if resp.TLS != nil {
+		verboseLog.Printf("< %s TLS %s %s server-name=%s", req.URL, tls.VersionName(resp.TLS.Version), tls.CipherSuiteName(resp.TLS.CipherSuite), resp.TLS.ServerName)
+	}
+	for k, vs := range resp.Header {
+		for _, v := range vs {
+			debugLog.Printf("< %s %s: %s", req.URL, k, v)
+		}
+	}
+}
@@ -0,0 +1,50 @@
+package main
+
+import (
+	"fmt"
+	"net/url"
+	"regexp"
+)
+
+var pathVersionRe = regexp.MustCompile(`/v\d+(/|$)`)
+
+// versionVariants returns copies of r with the first /vN/ segment of its
+// URL's path replaced by v1 through v10, excluding r's own version. The
+// host and query are left alone. It returns nil if the path has no version
+// segment.
+func versionVariants(r request) []request {
+	u, err := url.Parse(r.url)
+	if err != nil {
+		return nil
+	}
+	p := u.EscapedPath()
+
+	loc := pathVersionRe.FindStringSubmatchIndex(p)
+	if loc == nil {
+		return nil
+	}
+	// loc[2:4] is the trailing slash group, so the version itself runs
+	// from after the leading slash up to it.
+	start, end := loc[0]+1, loc[2]
+	current := p[start:end]
+
+	var variants []request
+	for n := 1; n <= 10; n++ {
+		v := fmt.Sprintf("v%d", n)
+		if v == current {
+			continue
+		}
+
+		vu := *u
+		vu.RawPath = p[:start] + v + p[end:]
+		vu.Path, err = url.PathUnescape(vu.RawPath)
+		if err != nil {
+			return nil
+		}
+
+		vr := r
+		vr.url = vu.String()
+		variants = append(variants, vr)
+	}
+	return variants
+}
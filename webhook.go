@@ -0,0 +1,74 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"sync"
+	"time"
+)
+
+// webhookTimeout bounds each --webhook call so a slow endpoint can't hold
+// up the end of a run.
+const webhookTimeout = 3 * time.Second
+
+// webhook POSTs a JSON notification to a URL for every --match hit. Calls
+// are made in the background with their own client, and failures are only
+// logged.
+type webhook struct {
+	url    string
+	client *http.Client
+	wg     sync.WaitGroup
+}
+
+type webhookPayload struct {
+	URL    string `json:"url"`
+	Status int    `json:"status"`
+	Match  string `json:"match"`
+}
+
+func newWebhook(rawURL string) (*webhook, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, err
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return nil, fmt.Errorf("webhook must be an http or https URL: %s", rawURL)
+	}
+
+	return &webhook{url: rawURL, client: &http.Client{Timeout: webhookTimeout}}, nil
+}
+
+// Notify sends the payload for a match of match in the response to
+// rawURL without waiting for the webhook to answer.
+func (w *webhook) Notify(rawURL string, status int, match string) {
+	payload, err := json.Marshal(webhookPayload{URL: rawURL, Status: status, Match: match})
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "failed to encode webhook payload: %s\n", err)
+		return
+	}
+
+	w.wg.Add(1)
+	go func() {
+		defer w.wg.Done()
+
+		resp, err := w.client.Post(w.url, "application/json", bytes.NewReader(payload))
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "webhook failed: %s\n", err)
+			return
+		}
+		resp.Body.Close()
+
+		if resp.StatusCode >= 300 {
+			fmt.Fprintf(os.Stderr, "webhook failed: %s\n", resp.Status)
+		}
+	}()
+}
+
+// Wait blocks until every notification has been sent or has failed.
+func (w *webhook) Wait() {
+	w.wg.Wait()
+}
